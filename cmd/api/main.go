@@ -1,7 +1,8 @@
 package main
 
 import (
-	"fmt"
+	"os"
+
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/server"
 )
 
@@ -12,6 +13,12 @@ func main() {
 	app.RegisterRoutes()
 
 	if err := app.Serve(app.Config.Port); err != nil {
-		fmt.Println("Fatal error occurred", err)
+		app.Logger.Error().
+			Err(err).
+			Msg("Fatal error occurred")
+		os.Exit(1)
 	}
+
+	app.Logger.Info().
+		Msg("Shut down gracefully, bye!")
 }
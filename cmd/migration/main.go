@@ -2,24 +2,236 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"entgo.io/ent/dialect"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/rs/zerolog"
+
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/config"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/migrations"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/logger"
-	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
+const usage = `Manages the versioned migrations of the database
+
+Usage:
+  migration [up]                 applies the pending migrations
+  migration down N               reverts the last N applied migrations
+  migration status               lists the migrations and when they were applied
+  migration baseline [flags] VERSION
+                                 marks the migrations up to VERSION as applied
+                                 without running them, for databases created
+                                 before migrations were versioned, as long as
+                                 their schema matches the migrations
+  migration diff [flags] NAME    writes the migration NAME bringing the
+                                 migrations up to date with the ent schema
+`
+
+// Where the migrations of each driver are kept, relative to the repository
+const migrationsDir = "internal/database/repositories/postgresql/migrations"
+
 func main() {
 	log := logger.New(true)
 
 	c := config.New()
+	if c.DB.Driver == config.DriverMemory {
+		log.Info().
+			Msg("nothing to migrate, the memory driver needs no schema")
+		return
+	}
+
+	var client *postgresql.PostgresqlClient
+	if c.DB.Driver == config.DriverSQLite {
+		client = postgresql.NewSQLite(c.DB.String(), log)
+	} else {
+		client = postgresql.New(c.DB.String(), log)
+	}
+
+	migrator, err := client.Migrator()
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Failed to load migrations")
+	}
+
+	ctx := context.Background()
+	args := os.Args[1:]
+	command := "up"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		logMigrations(log, "applied", applied)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Msg("Failed to apply migrations")
+		}
+
+		log.Info().
+			Int("applied", len(applied)).
+			Msg("migration succesfully executed, bye!")
+	case "down":
+		n, err := strconv.Atoi(argument(args, "N"))
+		if err != nil || n < 1 {
+			exitWithUsage("N must be a positive number")
+		}
+
+		reverted, err := migrator.Down(ctx, n)
+		logMigrations(log, "reverted", reverted)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Msg("Failed to revert migrations")
+		}
+
+		log.Info().
+			Int("reverted", len(reverted)).
+			Msg("migrations succesfully reverted, bye!")
+	case "status":
+		revisions, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Msg("Failed to read the migrations status")
+		}
+
+		for _, rev := range revisions {
+			applied := "pending"
+			if rev.AppliedAt != nil {
+				applied = rev.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%s_%s\t%s\n", rev.Version, rev.Name, applied)
+		}
+	case "baseline":
+		flags := flag.NewFlagSet("baseline", flag.ExitOnError)
+		devURL := flags.String("dev-url", "", "URL of an empty Postgres database to replay the migrations on, required for postgres")
+		flags.Parse(args)
+		version := argument(flags.Args(), "VERSION")
+
+		dev, _ := openDev(log, c.DB.Driver, *devURL)
+		defer dev.Close()
+
+		marked, err := migrator.Baseline(ctx, version, dev)
+		logMigrations(log, "marked as applied", marked)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Msg("Failed to baseline migrations")
+		}
+	case "diff":
+		diff(ctx, log, c.DB.Driver, args)
+	default:
+		exitWithUsage(fmt.Sprintf("unknown command %q", command))
+	}
+}
 
-	client := postgresql.New(c.DB.String(), log)
-	if err := client.CreateDB(context.Background(), log); err != nil {
+// Writes the migration from the ent schema, replaying the existing ones on a
+// scratch database
+func diff(ctx context.Context, log *zerolog.Logger, driver string, args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "print the migration instead of writing it")
+	devURL := flags.String("dev-url", "", "URL of an empty Postgres database to replay the migrations on, required for postgres")
+	flags.Parse(args)
+	name := argument(flags.Args(), "NAME")
+
+	dev, dialectName := openDev(log, driver, *devURL)
+	defer dev.Close()
+
+	src, err := migrations.Dir(dialectName)
+	if err != nil {
 		log.Fatal().
 			Err(err).
-			Msg("Failed to create database")
+			Msg("Failed to load migrations")
+	}
+
+	files, err := migrations.Diff(ctx, dev, dialectName, src, name)
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Failed to compute the migration")
+	}
+	if len(files) == 0 {
+		log.Info().
+			Msg("migrations are up to date with the schema, nothing to write")
+		return
+	}
+
+	dir := filepath.Join(migrationsDir, driver)
+	for _, f := range files {
+		if *dryRun {
+			fmt.Printf("-- %s\n%s\n", f.Name, f.Content)
+			continue
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, f.Name), f.Content, 0o644); err != nil {
+			log.Fatal().
+				Err(err).
+				Msg("Failed to write the migration")
+		}
+		log.Info().
+			Str("file", filepath.Join(dir, f.Name)).
+			Msg("migration file written")
+	}
+}
+
+// Scratch database of `driver` to replay the migrations on, an in-memory one
+// for SQLite, along with its ent dialect
+func openDev(log *zerolog.Logger, driver string, devURL string) (*sql.DB, string) {
+	var dev *sql.DB
+	var dialectName string
+	var err error
+	switch driver {
+	case config.DriverSQLite:
+		dialectName = dialect.SQLite
+		dev, err = sql.Open("sqlite", ":memory:?_pragma=foreign_keys(1)")
+	default:
+		if devURL == "" {
+			exitWithUsage("--dev-url is required for postgres")
+		}
+		dialectName = dialect.Postgres
+		dev, err = sql.Open("pgx", devURL)
 	}
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Failed to open the dev database")
+	}
+	// Every connection would get its own in-memory SQLite database
+	dev.SetMaxOpenConns(1)
+
+	return dev, dialectName
+}
+
+func argument(args []string, name string) string {
+	if len(args) != 1 {
+		exitWithUsage(fmt.Sprintf("expected a single %s argument", name))
+	}
+
+	return args[0]
+}
+
+func logMigrations(log *zerolog.Logger, action string, done []migrations.Migration) {
+	for _, m := range done {
+		log.Info().
+			Str("version", m.Version).
+			Str("name", m.Name).
+			Msg("migration " + action)
+	}
+}
 
-	log.Info().
-		Msg("migration succesfully executed, bye!")
+func exitWithUsage(reason string) {
+	fmt.Fprintf(os.Stderr, "%s\n\n%s", reason, usage)
+	os.Exit(2)
 }
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/config"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/logger"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// Permanently removes the users and posts soft deleted longer than
+// `CHALLENGE_SOFT_DELETE_RETENTION` ago, along with the expired idempotency
+// keys
+func main() {
+	log := logger.New(true)
+
+	c := config.New()
+	if c.DB.Driver == config.DriverMemory {
+		log.Info().
+			Msg("nothing to purge, the memory driver keeps its data in the API process")
+		return
+	}
+
+	before := time.Now().Add(-c.SoftDeleteRetention)
+
+	var client *postgresql.PostgresqlClient
+	if c.DB.Driver == config.DriverSQLite {
+		client = postgresql.NewSQLite(c.DB.String(), log)
+	} else {
+		client = postgresql.New(c.DB.String(), log)
+	}
+	ctx := context.Background()
+	users, posts, err := client.Purge(ctx, before, log)
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Failed to purge soft deleted rows")
+	}
+
+	keys, err := client.PurgeIdempotencyKeys(ctx, time.Now(), log)
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Failed to purge expired idempotency keys")
+	}
+
+	log.Info().
+		Int("users", users).
+		Int("posts", posts).
+		Int("idempotency_keys", keys).
+		Msg("purge succesfully executed, bye!")
+}
@@ -2,26 +2,100 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
+)
+
+// How long soft deleted users and posts are kept before being purged
+const defaultSoftDeleteRetention = 30 * 24 * time.Hour
+
+// How long the response to a request with an `Idempotency-Key` is replayed
+const defaultIdempotencyKeyTTL = 24 * time.Hour
+
+// How long the API keeps serving with its readiness failing after a SIGTERM,
+// so the load balancer stops sending it new requests
+const defaultShutdownDelay = 5 * time.Second
+
+// How long in-flight requests are waited for on shutdown
+const defaultShutdownTimeout = 25 * time.Second
+
+// Limits of the HTTP server, against slow clients and huge request bodies
+const (
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultReadTimeout       = 30 * time.Second
+	defaultWriteTimeout      = 30 * time.Second
+	defaultIdleTimeout       = 2 * time.Minute
+	defaultMaxBodyBytes      = 1 << 20
+	defaultMaxBatchItems     = 1000
+)
+
+// Databases the API can run on, see `CHALLENGE_DATABASE_DRIVER`
+const (
+	DriverPostgres = "postgres"
+	// Single file database, for running without a database server
+	DriverSQLite = "sqlite"
+	// Kept in memory and lost on restart, for local development
+	DriverMemory = "memory"
 )
 
 type DBConfig struct {
+	Driver   string
 	username string
 	password string
 	name     string
 	host     string
+	// File of the SQLite database
+	path string
 }
 
+// Data source name of the database, to be opened with the driver of the same
+// name
 func (dbc DBConfig) String() string {
+	if dbc.Driver == DriverSQLite {
+		return fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", dbc.path)
+	}
+
 	return fmt.Sprintf("postgresql://%s:%s@%s/%s?connect_timeout=5", dbc.username, dbc.password, dbc.host, dbc.name)
 }
 
 type Config struct {
 	IsDev bool
 	Port  uint
-	DB    DBConfig
+	// Port of the admin server exposing `/metrics`, 0 to expose it on `Port`
+	// along with the API instead
+	MetricsPort uint
+	DB          DBConfig
+	// Soft deleted rows older than this are removed by `cmd/purge`
+	SoftDeleteRetention time.Duration
+	// Retries with the same `Idempotency-Key` get the stored response for
+	// this long
+	IdempotencyKeyTTL time.Duration
+	// Time between failing the readiness check and closing the listener on
+	// shutdown
+	ShutdownDelay time.Duration
+	// In-flight requests still running this long after the listener is
+	// closed are cut off
+	ShutdownTimeout time.Duration
+	// Time a client gets to send the request headers
+	ReadHeaderTimeout time.Duration
+	// Time a client gets to send the whole request, body included
+	ReadTimeout time.Duration
+	// Time the API gets to send the response once the request headers are read
+	WriteTimeout time.Duration
+	// Time a keep-alive connection is kept open waiting for the next request
+	IdleTimeout time.Duration
+	// Request bodies larger than this are rejected with a `413 Content Too
+	// Large`
+	MaxBodyBytes int64
+	// Batches of `POST /users:batch` and `POST /posts:batch` with more items
+	// than this are rejected with a `422 Unprocessable Entity`
+	MaxBatchItems int
+	// URL of the OTLP/HTTP collector the traces are exported to, e.g.
+	// `http://collector:4318`. Traces are not exported when empty
+	TracingEndpoint string
 }
 
 type ConfigFunc func() Config
@@ -40,6 +114,86 @@ func fetchFromEnvironment() Config {
 		panic(fmt.Sprintf("could not parse `CHALLENGE_SERVER_PORT`: %v", err))
 	}
 
+	var metricsPort uint64
+	if raw := os.Getenv("CHALLENGE_METRICS_PORT"); raw != "" {
+		metricsPort, err = strconv.ParseUint(raw, 10, 32)
+		if err != nil || metricsPort == port {
+			panic(fmt.Sprintf("could not parse `CHALLENGE_METRICS_PORT` as a port other than `CHALLENGE_SERVER_PORT`: %v", raw))
+		}
+	}
+
+	maxBodyBytes := int64(defaultMaxBodyBytes)
+	if raw := os.Getenv("CHALLENGE_SERVER_MAX_BODY_BYTES"); raw != "" {
+		maxBodyBytes, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || maxBodyBytes <= 0 {
+			panic(fmt.Sprintf("could not parse `CHALLENGE_SERVER_MAX_BODY_BYTES` as a positive number: %v", raw))
+		}
+	}
+
+	maxBatchItems := defaultMaxBatchItems
+	if raw := os.Getenv("CHALLENGE_SERVER_MAX_BATCH_ITEMS"); raw != "" {
+		maxBatchItems, err = strconv.Atoi(raw)
+		if err != nil || maxBatchItems <= 0 {
+			panic(fmt.Sprintf("could not parse `CHALLENGE_SERVER_MAX_BATCH_ITEMS` as a positive number: %v", raw))
+		}
+	}
+
+	tracingEndpoint := os.Getenv("CHALLENGE_TRACING_ENDPOINT")
+	if tracingEndpoint != "" {
+		u, err := url.Parse(tracingEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			panic(fmt.Sprintf("could not parse `CHALLENGE_TRACING_ENDPOINT` as an http(s) URL: %v", tracingEndpoint))
+		}
+	}
+
+	config := Config{
+		IsDev:               isDev,
+		Port:                uint(port),
+		MetricsPort:         uint(metricsPort),
+		SoftDeleteRetention: durationFromEnvironment("CHALLENGE_SOFT_DELETE_RETENTION", defaultSoftDeleteRetention, true),
+		IdempotencyKeyTTL:   durationFromEnvironment("CHALLENGE_IDEMPOTENCY_KEY_TTL", defaultIdempotencyKeyTTL, false),
+		ShutdownDelay:       durationFromEnvironment("CHALLENGE_SHUTDOWN_DELAY", defaultShutdownDelay, true),
+		ShutdownTimeout:     durationFromEnvironment("CHALLENGE_SHUTDOWN_TIMEOUT", defaultShutdownTimeout, false),
+		ReadHeaderTimeout:   durationFromEnvironment("CHALLENGE_SERVER_READ_HEADER_TIMEOUT", defaultReadHeaderTimeout, false),
+		ReadTimeout:         durationFromEnvironment("CHALLENGE_SERVER_READ_TIMEOUT", defaultReadTimeout, false),
+		WriteTimeout:        durationFromEnvironment("CHALLENGE_SERVER_WRITE_TIMEOUT", defaultWriteTimeout, false),
+		IdleTimeout:         durationFromEnvironment("CHALLENGE_SERVER_IDLE_TIMEOUT", defaultIdleTimeout, false),
+		MaxBodyBytes:        maxBodyBytes,
+		MaxBatchItems:       maxBatchItems,
+		TracingEndpoint:     tracingEndpoint,
+	}
+
+	switch driver := strings.ToLower(os.Getenv("CHALLENGE_DATABASE_DRIVER")); driver {
+	case "", DriverPostgres:
+		config.DB = fetchPostgresFromEnvironment()
+	case DriverSQLite:
+		config.DB = fetchSQLiteFromEnvironment()
+	case DriverMemory:
+		config.DB = DBConfig{Driver: DriverMemory}
+	default:
+		panic(fmt.Sprintf("unknown database driver `CHALLENGE_DATABASE_DRIVER`: %v", driver))
+	}
+
+	return config
+}
+
+// Duration set in the environment variable `key`, `fallback` when unset.
+// Zero is only accepted if `allowZero`
+func durationFromEnvironment(key string, fallback time.Duration, allowZero bool) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 || (d == 0 && !allowZero) {
+		panic(fmt.Sprintf("could not parse `%s` as a positive duration: %v", key, raw))
+	}
+
+	return d
+}
+
+func fetchPostgresFromEnvironment() DBConfig {
 	dbHost := os.Getenv("CHALLENGE_DATABASE_HOST")
 	if dbHost == "" {
 		panic("database host `CHALLENGE_DATABASE_HOST` is not set")
@@ -60,14 +214,23 @@ func fetchFromEnvironment() Config {
 		panic("database password `CHALLENGE_DATABASE_PASSWORD` is not set")
 	}
 
-	return Config{
-		IsDev: isDev,
-		Port:  uint(port),
-		DB: DBConfig{
-			username: dbUsername,
-			password: dbPassword,
-			name:     dbName,
-			host:     dbHost,
-		},
+	return DBConfig{
+		Driver:   DriverPostgres,
+		username: dbUsername,
+		password: dbPassword,
+		name:     dbName,
+		host:     dbHost,
+	}
+}
+
+func fetchSQLiteFromEnvironment() DBConfig {
+	dbPath := os.Getenv("CHALLENGE_DATABASE_PATH")
+	if dbPath == "" {
+		panic("database file `CHALLENGE_DATABASE_PATH` is not set")
+	}
+
+	return DBConfig{
+		Driver: DriverSQLite,
+		path:   dbPath,
 	}
 }
@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -65,6 +66,189 @@ func Test_fetchFromEnvironment(t *testing.T) {
 		})
 	}
 
+	t.Run("should default the soft delete retention to 30 days", func(t *testing.T) {
+		config := fetchFromEnvironment()
+		assert.Equal(t, 30*24*time.Hour, config.SoftDeleteRetention)
+	})
+
+	t.Run("should fetch the soft delete retention from environment when set", func(t *testing.T) {
+		t.Setenv("CHALLENGE_SOFT_DELETE_RETENTION", "48h")
+		config := fetchFromEnvironment()
+		assert.Equal(t, 48*time.Hour, config.SoftDeleteRetention)
+	})
+
+	t.Run("should validate the soft delete retention is a duration", func(t *testing.T) {
+		assert.Panics(t, func() {
+			t.Setenv("CHALLENGE_SOFT_DELETE_RETENTION", "a month")
+			fetchFromEnvironment()
+		}, "should have panicked")
+	})
+
+	t.Run("should default the idempotency key TTL to a day", func(t *testing.T) {
+		config := fetchFromEnvironment()
+		assert.Equal(t, 24*time.Hour, config.IdempotencyKeyTTL)
+	})
+
+	t.Run("should fetch the idempotency key TTL from environment when set", func(t *testing.T) {
+		t.Setenv("CHALLENGE_IDEMPOTENCY_KEY_TTL", "1h")
+		config := fetchFromEnvironment()
+		assert.Equal(t, time.Hour, config.IdempotencyKeyTTL)
+	})
+
+	t.Run("should validate the idempotency key TTL is a positive duration", func(t *testing.T) {
+		assert.Panics(t, func() {
+			t.Setenv("CHALLENGE_IDEMPOTENCY_KEY_TTL", "0s")
+			fetchFromEnvironment()
+		}, "should have panicked")
+	})
+
+	t.Run("should default the shutdown delay and timeout", func(t *testing.T) {
+		config := fetchFromEnvironment()
+		assert.Equal(t, 5*time.Second, config.ShutdownDelay)
+		assert.Equal(t, 25*time.Second, config.ShutdownTimeout)
+	})
+
+	t.Run("should fetch the shutdown delay and timeout from environment when set", func(t *testing.T) {
+		t.Setenv("CHALLENGE_SHUTDOWN_DELAY", "0s")
+		t.Setenv("CHALLENGE_SHUTDOWN_TIMEOUT", "1m")
+		config := fetchFromEnvironment()
+		assert.Equal(t, time.Duration(0), config.ShutdownDelay)
+		assert.Equal(t, time.Minute, config.ShutdownTimeout)
+	})
+
+	t.Run("should validate the shutdown delay is a duration", func(t *testing.T) {
+		assert.Panics(t, func() {
+			t.Setenv("CHALLENGE_SHUTDOWN_DELAY", "a while")
+			fetchFromEnvironment()
+		}, "should have panicked")
+	})
+
+	t.Run("should validate the shutdown timeout is a positive duration", func(t *testing.T) {
+		assert.Panics(t, func() {
+			t.Setenv("CHALLENGE_SHUTDOWN_TIMEOUT", "0s")
+			fetchFromEnvironment()
+		}, "should have panicked")
+	})
+
+	t.Run("should default the server limits", func(t *testing.T) {
+		config := fetchFromEnvironment()
+		assert.Equal(t, 5*time.Second, config.ReadHeaderTimeout)
+		assert.Equal(t, 30*time.Second, config.ReadTimeout)
+		assert.Equal(t, 30*time.Second, config.WriteTimeout)
+		assert.Equal(t, 2*time.Minute, config.IdleTimeout)
+		assert.Equal(t, int64(1<<20), config.MaxBodyBytes)
+		assert.Equal(t, 1000, config.MaxBatchItems)
+	})
+
+	t.Run("should fetch the server limits from environment when set", func(t *testing.T) {
+		t.Setenv("CHALLENGE_SERVER_READ_HEADER_TIMEOUT", "1s")
+		t.Setenv("CHALLENGE_SERVER_READ_TIMEOUT", "2s")
+		t.Setenv("CHALLENGE_SERVER_WRITE_TIMEOUT", "3s")
+		t.Setenv("CHALLENGE_SERVER_IDLE_TIMEOUT", "4s")
+		t.Setenv("CHALLENGE_SERVER_MAX_BODY_BYTES", "1024")
+		t.Setenv("CHALLENGE_SERVER_MAX_BATCH_ITEMS", "10")
+		config := fetchFromEnvironment()
+		assert.Equal(t, time.Second, config.ReadHeaderTimeout)
+		assert.Equal(t, 2*time.Second, config.ReadTimeout)
+		assert.Equal(t, 3*time.Second, config.WriteTimeout)
+		assert.Equal(t, 4*time.Second, config.IdleTimeout)
+		assert.Equal(t, int64(1024), config.MaxBodyBytes)
+		assert.Equal(t, 10, config.MaxBatchItems)
+	})
+
+	t.Run("should validate the server timeouts are positive durations", func(t *testing.T) {
+		assert.Panics(t, func() {
+			t.Setenv("CHALLENGE_SERVER_READ_TIMEOUT", "0s")
+			fetchFromEnvironment()
+		}, "should have panicked")
+	})
+
+	t.Run("should validate the maximum body size is a positive number", func(t *testing.T) {
+		assert.Panics(t, func() {
+			t.Setenv("CHALLENGE_SERVER_MAX_BODY_BYTES", "1MB")
+			fetchFromEnvironment()
+		}, "should have panicked")
+	})
+
+	t.Run("should validate the maximum batch size is a positive number", func(t *testing.T) {
+		assert.Panics(t, func() {
+			t.Setenv("CHALLENGE_SERVER_MAX_BATCH_ITEMS", "0")
+			fetchFromEnvironment()
+		}, "should have panicked")
+	})
+
+	t.Run("should expose the metrics on the server port by default", func(t *testing.T) {
+		config := fetchFromEnvironment()
+		assert.Equal(t, uint(0), config.MetricsPort)
+	})
+
+	t.Run("should fetch the metrics port from environment when set", func(t *testing.T) {
+		t.Setenv("CHALLENGE_METRICS_PORT", "9090")
+		config := fetchFromEnvironment()
+		assert.Equal(t, uint(9090), config.MetricsPort)
+	})
+
+	t.Run("should validate the metrics port is not the server port", func(t *testing.T) {
+		assert.Panics(t, func() {
+			t.Setenv("CHALLENGE_METRICS_PORT", "3000")
+			fetchFromEnvironment()
+		}, "should have panicked")
+	})
+
+	t.Run("should not export traces by default", func(t *testing.T) {
+		config := fetchFromEnvironment()
+		assert.Empty(t, config.TracingEndpoint)
+	})
+
+	t.Run("should fetch the tracing endpoint from environment when set", func(t *testing.T) {
+		t.Setenv("CHALLENGE_TRACING_ENDPOINT", "http://collector:4318")
+		config := fetchFromEnvironment()
+		assert.Equal(t, "http://collector:4318", config.TracingEndpoint)
+	})
+
+	t.Run("should validate the tracing endpoint is an http(s) URL", func(t *testing.T) {
+		assert.Panics(t, func() {
+			t.Setenv("CHALLENGE_TRACING_ENDPOINT", "collector:4318")
+			fetchFromEnvironment()
+		}, "should have panicked")
+	})
+
+	t.Run("should default the database driver to postgres", func(t *testing.T) {
+		config := fetchFromEnvironment()
+		assert.Equal(t, DriverPostgres, config.DB.Driver)
+	})
+
+	t.Run("should not require database credentials with the memory driver", func(t *testing.T) {
+		t.Setenv("CHALLENGE_DATABASE_DRIVER", "memory")
+		t.Setenv("CHALLENGE_DATABASE_HOST", "")
+		t.Setenv("CHALLENGE_DATABASE_PASSWORD", "")
+		config := fetchFromEnvironment()
+		assert.Equal(t, DBConfig{Driver: DriverMemory}, config.DB)
+	})
+
+	t.Run("should fetch the database file with the sqlite driver", func(t *testing.T) {
+		t.Setenv("CHALLENGE_DATABASE_DRIVER", "sqlite")
+		t.Setenv("CHALLENGE_DATABASE_PATH", "challenge.db")
+		t.Setenv("CHALLENGE_DATABASE_HOST", "")
+		config := fetchFromEnvironment()
+		assert.Equal(t, DBConfig{Driver: DriverSQLite, path: "challenge.db"}, config.DB)
+		assert.Equal(t, "file:challenge.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", config.DB.String())
+	})
+
+	t.Run("should validate the database file is set with the sqlite driver", func(t *testing.T) {
+		assert.Panics(t, func() {
+			t.Setenv("CHALLENGE_DATABASE_DRIVER", "sqlite")
+			fetchFromEnvironment()
+		}, "should have panicked")
+	})
+
+	t.Run("should validate the database driver is known", func(t *testing.T) {
+		assert.Panics(t, func() {
+			t.Setenv("CHALLENGE_DATABASE_DRIVER", "mongodb")
+			fetchFromEnvironment()
+		}, "should have panicked")
+	})
+
 	t.Run("should validate `port` is a valid number", func(t *testing.T) {
 		assert.Panics(t, func() {
 			t.Setenv("CHALLENGE_SERVER_PORT", "WRONG")
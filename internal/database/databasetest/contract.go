@@ -0,0 +1,740 @@
+// Package databasetest checks implementations of `database.DBRepository`
+// behave the same way, so the handlers can rely on any of them.
+package databasetest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/logger"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
+)
+
+// Zone of the times given to the repositories, which must not assume UTC
+var nonUTC = time.FixedZone("UTC-7", -7*60*60)
+
+// Factory returns an empty repository, cleaned up along with `t`
+type Factory func(t *testing.T) database.DBRepository
+
+// RunContract runs the behavior every `database.DBRepository` must have
+// against the repositories made by `newRepo`, one per subtest.
+func RunContract(t *testing.T, newRepo Factory) {
+	t.Run("users", func(t *testing.T) {
+		runUserContract(t, newRepo)
+	})
+	t.Run("posts", func(t *testing.T) {
+		runPostContract(t, newRepo)
+	})
+	t.Run("transactions", func(t *testing.T) {
+		runTxContract(t, newRepo)
+	})
+	t.Run("idempotency keys", func(t *testing.T) {
+		runIdempotencyContract(t, newRepo)
+	})
+}
+
+func runUserContract(t *testing.T, newRepo Factory) {
+	t.Run("should create and get back a user", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+
+		created := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		found, err := repo.UserGetByID(ctx, created.ID, models.UserInclude{})
+
+		require.NoError(t, err)
+		assert.NotZero(t, created.ID)
+		assert.Equal(t, "John Doe", created.Name)
+		assert.Equal(t, "johnnydoe@gmail.com", created.Email)
+		assert.False(t, created.CreatedAt.IsZero())
+		assert.True(t, created.CreatedAt.Equal(created.UpdatedAt))
+		assertSameUser(t, created, found)
+	})
+
+	t.Run("should reject a taken email as a conflict on email", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		jane := createUser(t, ctx, repo, "janedoe@gmail.com")
+		taken := "johnnydoe@gmail.com"
+
+		_, createErr := repo.UserCreate(ctx, models.User{Name: "Johnny", Email: taken})
+		_, updateErr := repo.UserUpdate(ctx, models.UserUpdate{ID: &jane.ID, Name: jane.Name, Email: taken})
+		_, patchErr := repo.UserPatch(ctx, models.UserPatch{ID: &jane.ID, Email: &taken})
+
+		for _, err := range []error{createErr, updateErr, patchErr} {
+			var conflict *database.ConflictError
+			require.ErrorAs(t, err, &conflict)
+			assert.ErrorIs(t, err, database.ErrConflict)
+			assert.Equal(t, "email", conflict.Field)
+		}
+	})
+
+	t.Run("should create users in bulk, all of them or none", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		john := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+
+		created, err := repo.UserCreateBulk(ctx, []models.User{
+			{Name: "Jane Doe", Email: "janedoe@gmail.com"},
+			{Name: "Daniel Levy Moreno", Email: "danielmorenolevy@gmail.com"},
+		}, models.BulkOptions{})
+		require.NoError(t, err)
+		_, failedErr := repo.UserCreateBulk(ctx, []models.User{
+			{Name: "Rick Doe", Email: "rickdoe@gmail.com"},
+			{Name: "John Doe", Email: "johnnydoe@gmail.com"},
+			{Name: "Jane Doe", Email: "janedoe2@gmail.com"},
+			{Name: "Jane Doe", Email: "janedoe2@gmail.com"},
+		}, models.BulkOptions{})
+		users, err := repo.UserGetAll(ctx, models.UserQuery{Pagination: models.Pagination{Limit: 10}})
+		require.NoError(t, err)
+
+		require.Len(t, created, 2)
+		assert.Equal(t, "janedoe@gmail.com", created[0].Email)
+		assert.Equal(t, "danielmorenolevy@gmail.com", created[1].Email)
+		assert.True(t, created[0].CreatedAt.Equal(created[0].UpdatedAt))
+		var bulkErr *database.BulkError
+		require.ErrorAs(t, failedErr, &bulkErr)
+		assert.Len(t, bulkErr.Errors, 2)
+		assert.ErrorIs(t, bulkErr.Errors[1], database.ErrConflict)
+		assert.ErrorIs(t, bulkErr.Errors[3], database.ErrConflict)
+		assert.Equal(t, []uint64{john.ID, created[0].ID, created[1].ID}, userIDs(users.Items))
+	})
+
+	t.Run("should create the users it can in bulk when partial", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		createUser(t, ctx, repo, "johnnydoe@gmail.com")
+
+		created, err := repo.UserCreateBulk(ctx, []models.User{
+			{Name: "John Doe", Email: "johnnydoe@gmail.com"},
+			{Name: "Jane Doe", Email: "janedoe@gmail.com"},
+		}, models.BulkOptions{Partial: true})
+
+		var bulkErr *database.BulkError
+		require.ErrorAs(t, err, &bulkErr)
+		assert.Len(t, bulkErr.Errors, 1)
+		assert.ErrorIs(t, bulkErr.Errors[0], database.ErrConflict)
+		require.Len(t, created, 2)
+		assert.Nil(t, created[0])
+		require.NotNil(t, created[1])
+		found, err := repo.UserGetByID(ctx, created[1].ID, models.UserInclude{})
+		require.NoError(t, err)
+		assertSameUser(t, created[1], found)
+	})
+
+	t.Run("should report missing users as not found", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		id := uint64(42)
+		name := "Johnny"
+
+		_, getErr := repo.UserGetByID(ctx, id, models.UserInclude{})
+		_, postsErr := repo.UserGetPosts(ctx, id, models.PostQuery{Pagination: models.Pagination{Limit: 10}})
+		_, updateErr := repo.UserUpdate(ctx, models.UserUpdate{ID: &id, Name: name, Email: "johnny@gmail.com"})
+		_, patchErr := repo.UserPatch(ctx, models.UserPatch{ID: &id, Name: &name})
+		deleteErr := repo.UserDeleteByID(ctx, id, models.DeleteOptions{})
+		_, restoreErr := repo.UserRestoreByID(ctx, id)
+
+		for _, err := range []error{getErr, postsErr, updateErr, patchErr, deleteErr, restoreErr} {
+			assert.ErrorIs(t, err, database.ErrNotFound)
+		}
+	})
+
+	t.Run("should replace a user on update and only the given fields on patch", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		u := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		name := "Johnny"
+
+		updated, updateErr := repo.UserUpdate(ctx, models.UserUpdate{ID: &u.ID, Name: "John", Email: "john@gmail.com"})
+		patched, patchErr := repo.UserPatch(ctx, models.UserPatch{ID: &u.ID, Name: &name})
+
+		require.NoError(t, updateErr)
+		assert.Equal(t, "John", updated.Name)
+		assert.Equal(t, "john@gmail.com", updated.Email)
+		require.NoError(t, patchErr)
+		assert.Equal(t, name, patched.Name)
+		assert.Equal(t, "john@gmail.com", patched.Email)
+		assert.True(t, u.CreatedAt.Equal(patched.CreatedAt))
+		assert.False(t, patched.UpdatedAt.Before(u.UpdatedAt))
+	})
+
+	t.Run("should only write when updated_at matches", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		u := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		stale := u.UpdatedAt.Add(-time.Second)
+		name := "Johnny"
+
+		_, updateErr := repo.UserUpdate(ctx, models.UserUpdate{ID: &u.ID, Name: name, Email: u.Email, IfUpdatedAt: &stale})
+		_, patchErr := repo.UserPatch(ctx, models.UserPatch{ID: &u.ID, Name: &name, IfUpdatedAt: &stale})
+		deleteErr := repo.UserDeleteByID(ctx, u.ID, models.DeleteOptions{IfUpdatedAt: &stale})
+		patched, err := repo.UserPatch(ctx, models.UserPatch{ID: &u.ID, Name: &name, IfUpdatedAt: &u.UpdatedAt})
+
+		for _, err := range []error{updateErr, patchErr, deleteErr} {
+			assert.ErrorIs(t, err, database.ErrPreconditionFailed)
+		}
+		require.NoError(t, err)
+		assert.Equal(t, name, patched.Name)
+	})
+
+	t.Run("should match updated_at in any time zone", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		u := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		updatedAt := u.UpdatedAt.In(nonUTC)
+		name := "Johnny"
+
+		patched, err := repo.UserPatch(ctx, models.UserPatch{ID: &u.ID, Name: &name, IfUpdatedAt: &updatedAt})
+
+		require.NoError(t, err)
+		assert.Equal(t, name, patched.Name)
+	})
+
+	t.Run("should list users by ID a page at a time", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		john := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		jane := createUser(t, ctx, repo, "janedoe@gmail.com")
+		daniel := createUser(t, ctx, repo, "daniel@levy.dev")
+
+		first, err := repo.UserGetAll(ctx, models.UserQuery{Pagination: models.Pagination{Limit: 2}})
+		require.NoError(t, err)
+		require.NotNil(t, first.Next)
+		second, err := repo.UserGetAll(ctx, models.UserQuery{Pagination: models.Pagination{Limit: 2, After: first.Next}})
+		require.NoError(t, err)
+
+		assert.Equal(t, []uint64{john.ID, jane.ID}, userIDs(first.Items))
+		assert.Equal(t, []uint64{daniel.ID}, userIDs(second.Items))
+		assert.Nil(t, second.Next)
+	})
+
+	t.Run("should embed the posts of users when requested", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		john := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		createUser(t, ctx, repo, "janedoe@gmail.com")
+		p := createPost(t, ctx, repo, john.ID, "coolio")
+
+		found, err := repo.UserGetByID(ctx, john.ID, models.UserInclude{Posts: true})
+		page, listErr := repo.UserGetAll(ctx, models.UserQuery{
+			Pagination: models.Pagination{Limit: 10},
+			Include:    models.UserInclude{Posts: true},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, found.Posts, 1)
+		assert.Equal(t, p.ID, found.Posts[0].ID)
+		require.NoError(t, listErr)
+		require.Len(t, page.Items, 2)
+		assert.Len(t, page.Items[0].Posts, 1)
+		assert.NotNil(t, page.Items[1].Posts)
+		assert.Empty(t, page.Items[1].Posts)
+	})
+
+	t.Run("should refuse to delete a user with posts without cascade", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		u := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		createPost(t, ctx, repo, u.ID, "coolio")
+		createPost(t, ctx, repo, u.ID, "coolio 2")
+
+		err := repo.UserDeleteByID(ctx, u.ID, models.DeleteOptions{})
+
+		var hasPosts *database.UserHasPostsError
+		require.ErrorAs(t, err, &hasPosts)
+		assert.Equal(t, 2, hasPosts.Posts)
+		_, getErr := repo.UserGetByID(ctx, u.ID, models.UserInclude{})
+		assert.NoError(t, getErr)
+	})
+
+	t.Run("should delete and restore a user along with their posts", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		u := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		p := createPost(t, ctx, repo, u.ID, "coolio")
+
+		require.NoError(t, repo.UserDeleteByID(ctx, u.ID, models.DeleteOptions{Cascade: true}))
+		_, getErr := repo.UserGetByID(ctx, u.ID, models.UserInclude{})
+		_, postErr := repo.PostGetByID(ctx, p.ID, models.PostInclude{})
+		_, takenErr := repo.UserCreate(ctx, models.User{Name: "Johnny", Email: u.Email})
+		restored, err := repo.UserRestoreByID(ctx, u.ID)
+
+		assert.ErrorIs(t, getErr, database.ErrNotFound)
+		assert.ErrorIs(t, postErr, database.ErrNotFound)
+		assert.ErrorIs(t, takenErr, database.ErrConflict)
+		require.NoError(t, err)
+		assert.Equal(t, u.ID, restored.ID)
+		_, postErr = repo.PostGetByID(ctx, p.ID, models.PostInclude{})
+		assert.NoError(t, postErr)
+	})
+
+	t.Run("should bump updated_at on delete and restore", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		u := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		p := createPost(t, ctx, repo, u.ID, "coolio")
+		name := "Johnny"
+
+		require.NoError(t, repo.UserDeleteByID(ctx, u.ID, models.DeleteOptions{Cascade: true}))
+		restored, err := repo.UserRestoreByID(ctx, u.ID)
+		require.NoError(t, err)
+		restoredPost, err := repo.PostGetByID(ctx, p.ID, models.PostInclude{})
+		require.NoError(t, err)
+		_, staleErr := repo.UserPatch(ctx, models.UserPatch{ID: &u.ID, Name: &name, IfUpdatedAt: &u.UpdatedAt})
+
+		assert.True(t, restored.UpdatedAt.After(u.UpdatedAt), "updated_at %v not after %v", restored.UpdatedAt, u.UpdatedAt)
+		assert.True(t, restoredPost.UpdatedAt.After(p.UpdatedAt), "updated_at %v not after %v", restoredPost.UpdatedAt, p.UpdatedAt)
+		assert.ErrorIs(t, staleErr, database.ErrPreconditionFailed)
+	})
+}
+
+func runPostContract(t *testing.T, newRepo Factory) {
+	t.Run("should create and get back a post", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		// A different ID than the post, so they can't be mixed up
+		jane := createUser(t, ctx, repo, "janedoe@gmail.com")
+
+		created := createPost(t, ctx, repo, jane.ID, "coolio")
+		found, err := repo.PostGetByID(ctx, created.ID, models.PostInclude{User: true})
+
+		require.NoError(t, err)
+		assert.NotZero(t, created.ID)
+		assert.Equal(t, jane.ID, created.UserID)
+		assert.Equal(t, "coolio", created.Title)
+		assert.Equal(t, "coolest content", created.Content)
+		assert.True(t, created.CreatedAt.Equal(created.UpdatedAt))
+		assert.Equal(t, created.ID, found.ID)
+		assert.Equal(t, jane.ID, found.UserID)
+		assert.True(t, created.CreatedAt.Equal(found.CreatedAt))
+		require.NotNil(t, found.User)
+		assertSameUser(t, jane, found.User)
+	})
+
+	t.Run("should require the user of a post to exist", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		u := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		require.NoError(t, repo.UserDeleteByID(ctx, u.ID, models.DeleteOptions{}))
+
+		_, missingErr := repo.PostCreate(ctx, models.Post{Title: "coolio", Content: "coolest content", UserID: 42})
+		_, deletedErr := repo.PostCreate(ctx, models.Post{Title: "coolio", Content: "coolest content", UserID: u.ID})
+
+		assert.ErrorIs(t, missingErr, database.ErrForeignKey)
+		assert.ErrorIs(t, deletedErr, database.ErrUserDeleted)
+	})
+
+	t.Run("should create posts in bulk, all of them or none", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		john := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		jane := createUser(t, ctx, repo, "janedoe@gmail.com")
+		require.NoError(t, repo.UserDeleteByID(ctx, jane.ID, models.DeleteOptions{}))
+
+		created, err := repo.PostCreateBulk(ctx, []models.Post{
+			{Title: "coolio", Content: "coolest content", UserID: john.ID},
+			{Title: "another coolio", Content: "another coolest content", UserID: john.ID},
+		}, models.BulkOptions{})
+		require.NoError(t, err)
+		_, failedErr := repo.PostCreateBulk(ctx, []models.Post{
+			{Title: "coolio", Content: "coolest content", UserID: john.ID},
+			{Title: "coolio", Content: "coolest content", UserID: jane.ID},
+			{Title: "coolio", Content: "coolest content", UserID: 999},
+		}, models.BulkOptions{})
+		posts, err := repo.PostGetAll(ctx, models.PostQuery{Pagination: models.Pagination{Limit: 10}})
+		require.NoError(t, err)
+
+		require.Len(t, created, 2)
+		assert.Equal(t, "coolio", created[0].Title)
+		assert.Equal(t, john.ID, created[0].UserID)
+		assert.Equal(t, "another coolio", created[1].Title)
+		var bulkErr *database.BulkError
+		require.ErrorAs(t, failedErr, &bulkErr)
+		assert.Len(t, bulkErr.Errors, 2)
+		assert.ErrorIs(t, bulkErr.Errors[1], database.ErrUserDeleted)
+		assert.ErrorIs(t, bulkErr.Errors[2], database.ErrForeignKey)
+		assert.Equal(t, []uint64{created[0].ID, created[1].ID}, postIDs(posts.Items))
+	})
+
+	t.Run("should create the posts it can in bulk when partial", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		u := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+
+		created, err := repo.PostCreateBulk(ctx, []models.Post{
+			{Title: "coolio", Content: "coolest content", UserID: 999},
+			{Title: "coolio", Content: "coolest content", UserID: u.ID},
+		}, models.BulkOptions{Partial: true})
+
+		var bulkErr *database.BulkError
+		require.ErrorAs(t, err, &bulkErr)
+		assert.Len(t, bulkErr.Errors, 1)
+		assert.ErrorIs(t, bulkErr.Errors[0], database.ErrForeignKey)
+		require.Len(t, created, 2)
+		assert.Nil(t, created[0])
+		require.NotNil(t, created[1])
+		found, err := repo.PostGetByID(ctx, created[1].ID, models.PostInclude{})
+		require.NoError(t, err)
+		assert.Equal(t, u.ID, found.UserID)
+	})
+
+	t.Run("should delete every post of a user", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		john := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		jane := createUser(t, ctx, repo, "janedoe@gmail.com")
+		createPost(t, ctx, repo, john.ID, "coolio")
+		deletedBefore := createPost(t, ctx, repo, john.ID, "another coolio")
+		kept := createPost(t, ctx, repo, jane.ID, "more coolio")
+		require.NoError(t, repo.PostDeleteByID(ctx, deletedBefore.ID, models.DeleteOptions{}))
+
+		deleted, err := repo.PostDeleteByUserID(ctx, john.ID)
+		require.NoError(t, err)
+		none, err := repo.PostDeleteByUserID(ctx, john.ID)
+		require.NoError(t, err)
+		_, missingErr := repo.PostDeleteByUserID(ctx, 999)
+		posts, err := repo.PostGetAll(ctx, models.PostQuery{Pagination: models.Pagination{Limit: 10}})
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, deleted)
+		assert.Zero(t, none)
+		assert.ErrorIs(t, missingErr, database.ErrNotFound)
+		assert.Equal(t, []uint64{kept.ID}, postIDs(posts.Items))
+	})
+
+	t.Run("should report missing posts as not found", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		id := uint64(42)
+		title := "coolio"
+
+		_, getErr := repo.PostGetByID(ctx, id, models.PostInclude{})
+		_, updateErr := repo.PostUpdate(ctx, models.PostUpdate{ID: &id, Title: title, Content: "coolest content"})
+		_, patchErr := repo.PostPatch(ctx, models.PostPatch{ID: &id, Title: &title})
+		deleteErr := repo.PostDeleteByID(ctx, id, models.DeleteOptions{})
+		_, restoreErr := repo.PostRestoreByID(ctx, id)
+
+		for _, err := range []error{getErr, updateErr, patchErr, deleteErr, restoreErr} {
+			assert.ErrorIs(t, err, database.ErrNotFound)
+		}
+	})
+
+	t.Run("should replace a post on update and only the given fields on patch", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		u := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		p := createPost(t, ctx, repo, u.ID, "coolio")
+		content := "even cooler content"
+
+		updated, updateErr := repo.PostUpdate(ctx, models.PostUpdate{ID: &p.ID, Title: "cool", Content: "cool content"})
+		patched, patchErr := repo.PostPatch(ctx, models.PostPatch{ID: &p.ID, Content: &content})
+
+		require.NoError(t, updateErr)
+		assert.Equal(t, "cool", updated.Title)
+		assert.Equal(t, "cool content", updated.Content)
+		require.NoError(t, patchErr)
+		assert.Equal(t, "cool", patched.Title)
+		assert.Equal(t, content, patched.Content)
+		assert.Equal(t, u.ID, patched.UserID)
+	})
+
+	t.Run("should only write when updated_at matches", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		u := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		p := createPost(t, ctx, repo, u.ID, "coolio")
+		stale := p.UpdatedAt.Add(-time.Second)
+		title := "cool"
+
+		_, updateErr := repo.PostUpdate(ctx, models.PostUpdate{ID: &p.ID, Title: title, Content: p.Content, IfUpdatedAt: &stale})
+		_, patchErr := repo.PostPatch(ctx, models.PostPatch{ID: &p.ID, Title: &title, IfUpdatedAt: &stale})
+		deleteErr := repo.PostDeleteByID(ctx, p.ID, models.DeleteOptions{IfUpdatedAt: &stale})
+		err := repo.PostDeleteByID(ctx, p.ID, models.DeleteOptions{IfUpdatedAt: &p.UpdatedAt})
+
+		for _, err := range []error{updateErr, patchErr, deleteErr} {
+			assert.ErrorIs(t, err, database.ErrPreconditionFailed)
+		}
+		assert.NoError(t, err)
+	})
+
+	t.Run("should match updated_at in any time zone", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		u := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		p := createPost(t, ctx, repo, u.ID, "coolio")
+		updatedAt := p.UpdatedAt.In(nonUTC)
+		title := "cool"
+
+		patched, err := repo.PostPatch(ctx, models.PostPatch{ID: &p.ID, Title: &title, IfUpdatedAt: &updatedAt})
+
+		require.NoError(t, err)
+		assert.Equal(t, title, patched.Title)
+	})
+
+	t.Run("should filter, sort and paginate posts", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		john := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		jane := createUser(t, ctx, repo, "janedoe@gmail.com")
+		b := createPost(t, ctx, repo, john.ID, "b")
+		createPost(t, ctx, repo, jane.ID, "a")
+		c := createPost(t, ctx, repo, john.ID, "c")
+		a := createPost(t, ctx, repo, john.ID, "a")
+
+		query := models.PostQuery{
+			Pagination: models.Pagination{Limit: 2},
+			UserID:     &john.ID,
+			Sort:       []models.SortField{{Field: models.PostFieldTitle, Desc: true}},
+		}
+		first, err := repo.PostGetAll(ctx, query)
+		require.NoError(t, err)
+		require.NotNil(t, first.Next)
+		query.After = first.Next
+		second, err := repo.PostGetAll(ctx, query)
+		require.NoError(t, err)
+		ofUser, err := repo.UserGetPosts(ctx, john.ID, models.PostQuery{Pagination: models.Pagination{Limit: 10}})
+		require.NoError(t, err)
+
+		assert.Equal(t, []uint64{c.ID, b.ID}, postIDs(first.Items))
+		assert.Equal(t, []uint64{a.ID}, postIDs(second.Items))
+		assert.Nil(t, second.Next)
+		assert.Equal(t, []uint64{b.ID, c.ID, a.ID}, postIDs(ofUser.Items))
+	})
+
+	t.Run("should filter posts by creation time in any time zone", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		u := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		p := createPost(t, ctx, repo, u.ID, "coolio")
+		after := p.CreatedAt.Add(-time.Second).In(nonUTC)
+		before := p.CreatedAt.Add(time.Second).In(nonUTC)
+
+		page, err := repo.PostGetAll(ctx, models.PostQuery{
+			Pagination:    models.Pagination{Limit: 10},
+			CreatedAfter:  &after,
+			CreatedBefore: &before,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{p.ID}, postIDs(page.Items))
+	})
+
+	t.Run("should delete and restore a post", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		u := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		p := createPost(t, ctx, repo, u.ID, "coolio")
+
+		require.NoError(t, repo.PostDeleteByID(ctx, p.ID, models.DeleteOptions{}))
+		_, getErr := repo.PostGetByID(ctx, p.ID, models.PostInclude{})
+		deleteErr := repo.PostDeleteByID(ctx, p.ID, models.DeleteOptions{})
+		restored, err := repo.PostRestoreByID(ctx, p.ID)
+
+		assert.ErrorIs(t, getErr, database.ErrNotFound)
+		assert.ErrorIs(t, deleteErr, database.ErrNotFound)
+		require.NoError(t, err)
+		assert.Equal(t, p.ID, restored.ID)
+	})
+
+	t.Run("should bump updated_at on delete and restore", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		u := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		p := createPost(t, ctx, repo, u.ID, "coolio")
+		title := "coolio 2"
+
+		require.NoError(t, repo.PostDeleteByID(ctx, p.ID, models.DeleteOptions{}))
+		restored, err := repo.PostRestoreByID(ctx, p.ID)
+		require.NoError(t, err)
+		_, staleErr := repo.PostPatch(ctx, models.PostPatch{ID: &p.ID, Title: &title, IfUpdatedAt: &p.UpdatedAt})
+
+		assert.True(t, restored.UpdatedAt.After(p.UpdatedAt), "updated_at %v not after %v", restored.UpdatedAt, p.UpdatedAt)
+		assert.ErrorIs(t, staleErr, database.ErrPreconditionFailed)
+	})
+
+	t.Run("should not restore a post whose user is deleted", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		u := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		p := createPost(t, ctx, repo, u.ID, "coolio")
+		require.NoError(t, repo.UserDeleteByID(ctx, u.ID, models.DeleteOptions{Cascade: true}))
+
+		_, err := repo.PostRestoreByID(ctx, p.ID)
+
+		assert.ErrorIs(t, err, database.ErrUserDeleted)
+	})
+}
+
+func runTxContract(t *testing.T, newRepo Factory) {
+	t.Run("should keep every change of a transaction returning nil", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		var u *models.User
+		var p *models.Post
+
+		err := repo.WithTx(ctx, func(ctx context.Context, tx database.DBRepository) error {
+			u = createUser(t, ctx, tx, "johnnydoe@gmail.com")
+			p = createPost(t, ctx, tx, u.ID, "coolio")
+			return nil
+		})
+		require.NoError(t, err)
+		posts, err := repo.UserGetPosts(ctx, u.ID, models.PostQuery{Pagination: models.Pagination{Limit: 10}})
+
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{p.ID}, postIDs(posts.Items))
+	})
+
+	t.Run("should discard every change of a transaction returning an error", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		var u *models.User
+		errFailed := errors.New("failed")
+
+		err := repo.WithTx(ctx, func(ctx context.Context, tx database.DBRepository) error {
+			u = createUser(t, ctx, tx, "johnnydoe@gmail.com")
+			createPost(t, ctx, tx, u.ID, "coolio")
+			return errFailed
+		})
+		_, getErr := repo.UserGetByID(ctx, u.ID, models.UserInclude{})
+		posts, listErr := repo.PostGetAll(ctx, models.PostQuery{Pagination: models.Pagination{Limit: 10}})
+
+		assert.ErrorIs(t, err, errFailed)
+		assert.ErrorIs(t, getErr, database.ErrNotFound)
+		require.NoError(t, listErr)
+		assert.Empty(t, posts.Items)
+		// The email is free again
+		createUser(t, ctx, repo, "johnnydoe@gmail.com")
+	})
+
+	t.Run("should discard every change of a transaction that panics", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+
+		assert.Panics(t, func() {
+			repo.WithTx(ctx, func(ctx context.Context, tx database.DBRepository) error {
+				createUser(t, ctx, tx, "johnnydoe@gmail.com")
+				panic("failed")
+			})
+		})
+		users, err := repo.UserGetAll(ctx, models.UserQuery{Pagination: models.Pagination{Limit: 10}})
+
+		require.NoError(t, err)
+		assert.Empty(t, users.Items)
+	})
+
+	t.Run("should join the transaction of the context", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		u := createUser(t, ctx, repo, "johnnydoe@gmail.com")
+		p := createPost(t, ctx, repo, u.ID, "coolio")
+		errFailed := errors.New("failed")
+
+		err := repo.WithTx(ctx, func(ctx context.Context, tx database.DBRepository) error {
+			// Both the repository of the transaction and the one it was
+			// started on take part in it when given its context
+			require.NoError(t, tx.WithTx(ctx, func(ctx context.Context, _ database.DBRepository) error {
+				return repo.PostDeleteByID(ctx, p.ID, models.DeleteOptions{})
+			}))
+			require.NoError(t, repo.UserDeleteByID(ctx, u.ID, models.DeleteOptions{}))
+			return errFailed
+		})
+		found, getErr := repo.UserGetByID(ctx, u.ID, models.UserInclude{Posts: true})
+
+		assert.ErrorIs(t, err, errFailed)
+		require.NoError(t, getErr)
+		assert.Equal(t, []uint64{p.ID}, postIDs(found.Posts))
+	})
+}
+
+func runIdempotencyContract(t *testing.T, newRepo Factory) {
+	t.Run("should hand a key to the first request claiming it", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		record := models.IdempotencyRecord{Key: "key", Fingerprint: "first", ExpiresAt: time.Now().Add(time.Hour)}
+
+		first, err := repo.IdempotencyKeyClaim(ctx, record)
+		require.NoError(t, err)
+		record.Fingerprint = "second"
+		second, err := repo.IdempotencyKeyClaim(ctx, record)
+		require.NoError(t, err)
+
+		assert.Nil(t, first)
+		require.NotNil(t, second)
+		assert.Equal(t, "first", second.Fingerprint)
+		assert.Zero(t, second.Status)
+	})
+
+	t.Run("should return the stored response to the next claims", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		record := models.IdempotencyRecord{Key: "key", Fingerprint: "first", ExpiresAt: time.Now().Add(time.Hour)}
+		_, err := repo.IdempotencyKeyClaim(ctx, record)
+		require.NoError(t, err)
+
+		record.Status = 201
+		record.Header = http.Header{"Content-Type": {"application/json; charset=utf-8"}}
+		record.Body = []byte(`{"id":1}`)
+		require.NoError(t, repo.IdempotencyKeyComplete(ctx, record))
+		held, err := repo.IdempotencyKeyClaim(ctx, record)
+		missingErr := repo.IdempotencyKeyComplete(ctx, models.IdempotencyRecord{Key: "other", Status: 201})
+
+		require.NoError(t, err)
+		require.NotNil(t, held)
+		assert.Equal(t, 201, held.Status)
+		assert.Equal(t, record.Header, held.Header)
+		assert.Equal(t, record.Body, held.Body)
+		assert.ErrorIs(t, missingErr, database.ErrNotFound)
+	})
+
+	t.Run("should hand out again keys that expired or were released", func(t *testing.T) {
+		repo, ctx := setup(t, newRepo)
+		expired := models.IdempotencyRecord{Key: "expired", Fingerprint: "first", ExpiresAt: time.Now().Add(-time.Minute)}
+		released := models.IdempotencyRecord{Key: "released", Fingerprint: "first", ExpiresAt: time.Now().Add(time.Hour)}
+		_, err := repo.IdempotencyKeyClaim(ctx, expired)
+		require.NoError(t, err)
+		_, err = repo.IdempotencyKeyClaim(ctx, released)
+		require.NoError(t, err)
+		require.NoError(t, repo.IdempotencyKeyRelease(ctx, released.Key))
+
+		expired.ExpiresAt = time.Now().Add(time.Hour)
+		expiredAgain, err := repo.IdempotencyKeyClaim(ctx, expired)
+		require.NoError(t, err)
+		releasedAgain, err := repo.IdempotencyKeyClaim(ctx, released)
+		require.NoError(t, err)
+
+		assert.Nil(t, expiredAgain)
+		assert.Nil(t, releasedAgain)
+	})
+}
+
+// HELPERS
+func setup(t *testing.T, newRepo Factory) (database.DBRepository, context.Context) {
+	t.Helper()
+
+	l := zerolog.Nop()
+
+	return newRepo(t), logger.WithContext(context.Background(), &l)
+}
+
+func createUser(t *testing.T, ctx context.Context, repo database.DBRepository, email string) *models.User {
+	t.Helper()
+
+	u, err := repo.UserCreate(ctx, models.User{Name: "John Doe", Email: email})
+	require.NoError(t, err)
+
+	return u
+}
+
+func createPost(t *testing.T, ctx context.Context, repo database.DBRepository, userID uint64, title string) *models.Post {
+	t.Helper()
+
+	p, err := repo.PostCreate(ctx, models.Post{Title: title, Content: "coolest content", UserID: userID})
+	require.NoError(t, err)
+
+	return p
+}
+
+func assertSameUser(t *testing.T, expected, actual *models.User) {
+	t.Helper()
+
+	assert.Equal(t, expected.ID, actual.ID)
+	assert.Equal(t, expected.Name, actual.Name)
+	assert.Equal(t, expected.Email, actual.Email)
+	assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt), "created_at %v != %v", expected.CreatedAt, actual.CreatedAt)
+	assert.True(t, expected.UpdatedAt.Equal(actual.UpdatedAt), "updated_at %v != %v", expected.UpdatedAt, actual.UpdatedAt)
+}
+
+func userIDs(users []*models.User) []uint64 {
+	ids := make([]uint64, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+
+	return ids
+}
+
+func postIDs(posts []*models.Post) []uint64 {
+	ids := make([]uint64, 0, len(posts))
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+
+	return ids
+}
@@ -2,22 +2,74 @@ package database
 
 import (
 	"context"
-	"database/sql"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
 )
 
-type DBRepository interface {
-	Connection() *sql.DB
+// TxFunc is the body of a transaction. `tx` and the calls made with `ctx`
+// take part in it.
+type TxFunc func(ctx context.Context, tx DBRepository) error
+
+// HealthChecker reports whether the storage can serve requests
+type HealthChecker interface {
 	Ping(ctx context.Context) error
+}
+
+// Transactor groups calls to the repositories of a storage atomically
+type Transactor interface {
+	// Runs `fn` atomically, its changes are kept when it returns nil and
+	// discarded otherwise. Called with the context of a transaction, it
+	// joins that transaction instead of starting a new one.
+	WithTx(ctx context.Context, fn TxFunc) error
+}
+
+type UserRepository interface {
 	UserCreate(ctx context.Context, user models.User) (*models.User, error)
-	UserGetAll(ctx context.Context) ([]*models.User, error)
-	UserGetByID(ctx context.Context, id uint64) (*models.User, error)
-	UserDeleteByID(ctx context.Context, id uint64) error
+	// Creates the users in a single write. Returns them in the given order,
+	// nil for the ones that failed along with a `BulkError`.
+	UserCreateBulk(ctx context.Context, users []models.User, opts models.BulkOptions) ([]*models.User, error)
+	UserGetAll(ctx context.Context, query models.UserQuery) (*models.Page[*models.User], error)
+	UserGetByID(ctx context.Context, id uint64, include models.UserInclude) (*models.User, error)
+	UserGetPosts(ctx context.Context, id uint64, query models.PostQuery) (*models.Page[*models.Post], error)
+	UserDeleteByID(ctx context.Context, id uint64, opts models.DeleteOptions) error
 	UserUpdate(ctx context.Context, user models.UserUpdate) (*models.User, error)
+	UserPatch(ctx context.Context, patch models.UserPatch) (*models.User, error)
+	UserRestoreByID(ctx context.Context, id uint64) (*models.User, error)
+}
 
+type PostRepository interface {
 	PostCreate(ctx context.Context, post models.Post) (*models.Post, error)
-	PostGetAll(ctx context.Context) ([]*models.Post, error)
-	PostGetByID(ctx context.Context, id uint64) (*models.Post, error)
-	PostDeleteByID(ctx context.Context, id uint64) error
+	// Creates the posts in a single write. Returns them in the given order,
+	// nil for the ones that failed along with a `BulkError`.
+	PostCreateBulk(ctx context.Context, posts []models.Post, opts models.BulkOptions) ([]*models.Post, error)
+	PostGetAll(ctx context.Context, query models.PostQuery) (*models.Page[*models.Post], error)
+	PostGetByID(ctx context.Context, id uint64, include models.PostInclude) (*models.Post, error)
+	PostDeleteByID(ctx context.Context, id uint64, opts models.DeleteOptions) error
+	// Deletes every post of the user, returning how many were deleted
+	PostDeleteByUserID(ctx context.Context, userID uint64) (int, error)
 	PostUpdate(ctx context.Context, post models.PostUpdate) (*models.Post, error)
+	PostPatch(ctx context.Context, patch models.PostPatch) (*models.Post, error)
+	PostRestoreByID(ctx context.Context, id uint64) (*models.Post, error)
+}
+
+// IdempotencyRepository keeps the responses to the requests sent with an
+// `Idempotency-Key` header
+type IdempotencyRepository interface {
+	// Claims `record.Key` for the request of `record` and returns nil. When
+	// the key is held by a record that hasn't expired, returns that record
+	// instead.
+	IdempotencyKeyClaim(ctx context.Context, record models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+	// Stores the response to the request holding `record.Key`
+	IdempotencyKeyComplete(ctx context.Context, record models.IdempotencyRecord) error
+	// Frees the key, so the request can be retried with it
+	IdempotencyKeyRelease(ctx context.Context, key string) error
+}
+
+// DBRepository is a whole storage backend, the consumers depend on the
+// narrower interfaces it is made of.
+type DBRepository interface {
+	HealthChecker
+	Transactor
+	UserRepository
+	PostRepository
+	IdempotencyRepository
 }
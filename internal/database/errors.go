@@ -0,0 +1,103 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors every `DBRepository` reports its failures with, so callers don't
+// depend on the underlying driver. Implementations wrap the original error,
+// use `errors.Is` and `errors.As` to inspect them.
+var (
+	// ErrNotFound is returned when the resource doesn't exist, or is soft
+	// deleted.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is matched by every `ConflictError`.
+	ErrConflict = errors.New("conflict")
+	// ErrForeignKey is returned when a write references a resource that
+	// doesn't exist.
+	ErrForeignKey = errors.New("referenced resource doesn't exist")
+	// ErrUnavailable is returned when the database couldn't serve the
+	// request, e.g. it is unreachable.
+	ErrUnavailable = errors.New("database unavailable")
+	// ErrPreconditionFailed is returned when a conditional write finds the
+	// resource was modified since the version the caller expected.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrUserDeleted is returned when writing a post whose user is soft
+	// deleted.
+	ErrUserDeleted = errors.New("user is deleted")
+)
+
+// ConflictError is returned when a write breaks a uniqueness constraint.
+// Field is the conflicting field, empty when it can't be told.
+type ConflictError struct {
+	Field string
+	Err   error
+}
+
+func (e *ConflictError) Error() string {
+	if e.Field == "" {
+		return ErrConflict.Error()
+	}
+
+	return fmt.Sprintf("conflict on %s", e.Field)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// UserHasPostsError is returned when deleting a user who still owns posts
+// without cascading.
+type UserHasPostsError struct {
+	Posts int
+}
+
+func (e *UserHasPostsError) Error() string {
+	return fmt.Sprintf("user has %d posts", e.Posts)
+}
+
+// BulkError is returned by bulk writes when some of the items can't be
+// written. Errors holds why by index of the item in the batch, with the same
+// errors writing the item alone would return.
+type BulkError struct {
+	Errors map[int]error
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("%d items of the batch failed", len(e.Errors))
+}
+
+// IsUnexpected tells whether `err` is a failure of the database, e.g.
+// `ErrUnavailable`, rather than one of the errors above callers answer the
+// request with, like `ErrNotFound` or a `ConflictError`.
+func IsUnexpected(err error) bool {
+	var hasPosts *UserHasPostsError
+	var bulkErr *BulkError
+
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &bulkErr):
+		for _, itemErr := range bulkErr.Errors {
+			if IsUnexpected(itemErr) {
+				return true
+			}
+		}
+
+		return false
+	case errors.Is(err, ErrNotFound),
+		errors.Is(err, ErrConflict),
+		errors.Is(err, ErrForeignKey),
+		errors.Is(err, ErrPreconditionFailed),
+		errors.Is(err, ErrUserDeleted),
+		errors.As(err, &hasPosts):
+		return false
+	default:
+		return true
+	}
+}
@@ -2,167 +2,357 @@ package inmemory
 
 import (
 	"context"
-	"database/sql"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
 )
 
 type PingFunc func(context.Context) error
+type WithTxFunc func(context.Context, database.TxFunc) error
 
 type UserCreateFunc func(context.Context, models.User) (*models.User, error)
-type UserGetAllFunc func(context.Context) ([]*models.User, error)
-type UserGetByIDFunc func(context.Context, uint64) (*models.User, error)
-type UserDeleteByIDFunc func(context.Context, uint64) error
+type UserCreateBulkFunc func(context.Context, []models.User, models.BulkOptions) ([]*models.User, error)
+type UserGetAllFunc func(context.Context, models.UserQuery) (*models.Page[*models.User], error)
+type UserGetByIDFunc func(context.Context, uint64, models.UserInclude) (*models.User, error)
+type UserGetPostsFunc func(context.Context, uint64, models.PostQuery) (*models.Page[*models.Post], error)
+type UserDeleteByIDFunc func(context.Context, uint64, models.DeleteOptions) error
 type UserUpdateFunc func(context.Context, models.UserUpdate) (*models.User, error)
+type UserPatchFunc func(context.Context, models.UserPatch) (*models.User, error)
+type UserRestoreByIDFunc func(context.Context, uint64) (*models.User, error)
 
-var InMemoryDBPingFn PingFunc = func(c context.Context) error {
-	return nil
+type PostCreateFunc func(ctx context.Context, post models.Post) (*models.Post, error)
+type PostCreateBulkFunc func(ctx context.Context, posts []models.Post, opts models.BulkOptions) ([]*models.Post, error)
+type PostGetAllFunc func(ctx context.Context, query models.PostQuery) (*models.Page[*models.Post], error)
+type PostGetByIDFunc func(ctx context.Context, id uint64, include models.PostInclude) (*models.Post, error)
+type PostDeleteByIDFunc func(ctx context.Context, id uint64, opts models.DeleteOptions) error
+type PostDeleteByUserIDFunc func(ctx context.Context, userID uint64) (int, error)
+type PostUpdateFunc func(ctx context.Context, post models.PostUpdate) (*models.Post, error)
+type PostPatchFunc func(ctx context.Context, patch models.PostPatch) (*models.Post, error)
+type PostRestoreByIDFunc func(ctx context.Context, id uint64) (*models.Post, error)
+
+type IdempotencyKeyClaimFunc func(ctx context.Context, record models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+type IdempotencyKeyCompleteFunc func(ctx context.Context, record models.IdempotencyRecord) error
+type IdempotencyKeyReleaseFunc func(ctx context.Context, key string) error
+
+// Fault injection hooks, when set they are called instead of the store.
+// Tests override them to make the repository fail or to spy on its
+// arguments, and restore them to nil afterwards.
+var (
+	InMemoryDBPingFn                 PingFunc
+	InMemoryWithTxFn                 WithTxFunc
+	InMemoryUserCreateFn             UserCreateFunc
+	InMemoryUserCreateBulkFn         UserCreateBulkFunc
+	InMemoryUserGetAllFn             UserGetAllFunc
+	InMemoryUserGetByIDFn            UserGetByIDFunc
+	InMemoryUserGetPostsFn           UserGetPostsFunc
+	InMemoryUserDeleteByIDFn         UserDeleteByIDFunc
+	InMemoryUserUpdateFn             UserUpdateFunc
+	InMemoryUserPatchFn              UserPatchFunc
+	InMemoryUserRestoreByIDFn        UserRestoreByIDFunc
+	InMemoryPostCreateFn             PostCreateFunc
+	InMemoryPostCreateBulkFn         PostCreateBulkFunc
+	InMemoryPostGetAllFn             PostGetAllFunc
+	InMemoryPostGetByIDFn            PostGetByIDFunc
+	InMemoryPostDeleteByIDFn         PostDeleteByIDFunc
+	InMemoryPostDeleteByUserIDFn     PostDeleteByUserIDFunc
+	InMemoryPostUpdateFn             PostUpdateFunc
+	InMemoryPostPatchFn              PostPatchFunc
+	InMemoryPostRestoreByIDFn        PostRestoreByIDFunc
+	InMemoryIdempotencyKeyClaimFn    IdempotencyKeyClaimFunc
+	InMemoryIdempotencyKeyCompleteFn IdempotencyKeyCompleteFunc
+	InMemoryIdempotencyKeyReleaseFn  IdempotencyKeyReleaseFunc
+)
+
+// InMemoryDB is a `database.DBRepository` kept in memory, safe for concurrent
+// use. It behaves like Postgres: IDs are auto-incremented, emails are unique,
+// posts must belong to an existing user and deletes are soft.
+type InMemoryDB struct {
+	// The repository given to a transaction doesn't lock, `WithTx` holds the
+	// lock of the store for it
+	mu  rwLocker
+	now func() time.Time
+	*tables
 }
-var InMemoryUserCreateFn UserCreateFunc = func(ctx context.Context, user models.User) (*models.User, error) {
-	return &models.User{
-		ID:    1,
-		Name:  user.Name,
-		Email: user.Email,
-	}, nil
-}
-var InMemoryUserGetAllFn UserGetAllFunc = func(ctx context.Context) ([]*models.User, error) {
-	return []*models.User{
-		{
-			ID:    1,
-			Name:  "John Doe",
-			Email: "johnnydoe@gmail.com",
-		},
-		{
-			ID:    2,
-			Name:  "Daniel Levy Moreno",
-			Email: "danielmorenolevy@gmail.com",
-		},
-	}, nil
+
+// Contents of the store, shared with the repository of its transactions
+type tables struct {
+	users map[uint64]*userRow
+	posts map[uint64]*postRow
+	// By key, expired ones are replaced when claimed again
+	idempotencyKeys map[string]*models.IdempotencyRecord
+	lastUserID      uint64
+	lastPostID      uint64
+}
+
+type rwLocker interface {
+	sync.Locker
+	RLock()
+	RUnlock()
 }
-var InMemoryUserGetByIDFn UserGetByIDFunc = func(ctx context.Context, id uint64) (*models.User, error) {
-	return &models.User{
-		ID:    id,
-		Name:  "Daniel Levy Moreno",
-		Email: "danielmorenolevy@gmail.com",
-	}, nil
+
+// New returns an empty repository, whose timestamps are read from `now`
+func New(now func() time.Time) *InMemoryDB {
+	return &InMemoryDB{
+		mu:  &sync.RWMutex{},
+		now: now,
+		tables: &tables{
+			users:           map[uint64]*userRow{},
+			posts:           map[uint64]*postRow{},
+			idempotencyKeys: map[string]*models.IdempotencyRecord{},
+		},
+	}
 }
-var InMemoryUserDeleteByIDFn UserDeleteByIDFunc = func(ctx context.Context, i uint64) error {
+
+func (im *InMemoryDB) Ping(ctx context.Context) error {
+	if InMemoryDBPingFn != nil {
+		return InMemoryDBPingFn(ctx)
+	}
+
 	return nil
 }
-var InMemoryUserUpdateFn UserUpdateFunc = func(ctx context.Context, u models.UserUpdate) (*models.User, error) {
-	return &models.User{
-		ID:    1,
-		Name:  "Daniel Levy Moreno",
-		Email: "danielmorenolevy@gmail.com",
-	}, nil
+
+// Runs `fn` holding the lock of the store, on a snapshot restored unless it
+// returns nil. Transactions are thus serialized with every other call.
+func (im *InMemoryDB) WithTx(ctx context.Context, fn database.TxFunc) error {
+	if InMemoryWithTxFn != nil {
+		return InMemoryWithTxFn(ctx, fn)
+	}
+
+	// Joins the transaction of `ctx`, or the one `im` belongs to
+	if tx := im.in(ctx); tx.mu == (noLock{}) {
+		return fn(context.WithValue(ctx, ctxKeyTx{}, tx), tx)
+	}
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	snapshot := im.tables.clone()
+	committed := false
+	// Also rolls back when `fn` panics
+	defer func() {
+		if !committed {
+			*im.tables = *snapshot
+		}
+	}()
+
+	tx := &InMemoryDB{mu: noLock{}, now: im.now, tables: im.tables}
+	if err := fn(context.WithValue(ctx, ctxKeyTx{}, tx), tx); err != nil {
+		return err
+	}
+	committed = true
+
+	return nil
 }
 
-type PostCreateFunc func(ctx context.Context, post models.Post) (*models.Post, error)
-type PostGetAllFunc func(ctx context.Context) ([]*models.Post, error)
-type PostGetByIDFunc func(ctx context.Context, id uint64) (*models.Post, error)
-type PostDeleteByIDFunc func(ctx context.Context, id uint64) error
-type PostUpdateFunc func(ctx context.Context, post models.PostUpdate) (*models.Post, error)
+func (im *InMemoryDB) UserCreate(ctx context.Context, user models.User) (*models.User, error) {
+	if InMemoryUserCreateFn != nil {
+		return InMemoryUserCreateFn(ctx, user)
+	}
 
-var InMemoryPostCreateFn PostCreateFunc = func(ctx context.Context, post models.Post) (*models.Post, error) {
-	return &models.Post{
-		ID:      1,
-		Title:   "coolio",
-		Content: "coolest content",
-		UserID:  1,
-	}, nil
-}
-
-var InMemoryPostGetAllFn PostGetAllFunc = func(ctx context.Context) ([]*models.Post, error) {
-	return []*models.Post{
-		{
-			ID:      1,
-			Title:   "coolio",
-			Content: "coolest content",
-			UserID:  1,
-		},
-		{
-			ID:      2,
-			Title:   "another coolio",
-			Content: "another coolest content",
-			UserID:  1,
-		},
-		{
-			ID:      3,
-			Title:   "more coolio",
-			Content: "coolest content?",
-			UserID:  2,
-		},
-	}, nil
+	return im.in(ctx).userCreate(user)
 }
 
-var InMemoryPostGetByIDFn PostGetByIDFunc = func(ctx context.Context, id uint64) (*models.Post, error) {
-	return &models.Post{
-		ID:      id,
-		Title:   "coolio",
-		Content: "coolest content",
-		UserID:  1,
-	}, nil
+func (im *InMemoryDB) UserCreateBulk(ctx context.Context, users []models.User, opts models.BulkOptions) ([]*models.User, error) {
+	if InMemoryUserCreateBulkFn != nil {
+		return InMemoryUserCreateBulkFn(ctx, users, opts)
+	}
+
+	return im.in(ctx).userCreateBulk(users, opts)
 }
 
-var InMemoryPostDeleteByIDFn PostDeleteByIDFunc = func(ctx context.Context, id uint64) error {
-	return nil
+func (im *InMemoryDB) UserGetAll(ctx context.Context, query models.UserQuery) (*models.Page[*models.User], error) {
+	if InMemoryUserGetAllFn != nil {
+		return InMemoryUserGetAllFn(ctx, query)
+	}
+
+	return im.in(ctx).userGetAll(query)
 }
 
-var InMemoryPostUpdateFn PostUpdateFunc = func(ctx context.Context, post models.PostUpdate) (*models.Post, error) {
-	return &models.Post{
-		ID:      *post.ID,
-		Title:   "coolio",
-		Content: "coolest content",
-		UserID:  1,
-	}, nil
+func (im *InMemoryDB) UserGetByID(ctx context.Context, id uint64, include models.UserInclude) (*models.User, error) {
+	if InMemoryUserGetByIDFn != nil {
+		return InMemoryUserGetByIDFn(ctx, id, include)
+	}
+
+	return im.in(ctx).userGetByID(id, include)
 }
 
-type InMemoryDB struct{}
+func (im *InMemoryDB) UserGetPosts(ctx context.Context, id uint64, query models.PostQuery) (*models.Page[*models.Post], error) {
+	if InMemoryUserGetPostsFn != nil {
+		return InMemoryUserGetPostsFn(ctx, id, query)
+	}
 
-func (im *InMemoryDB) Connection() *sql.DB {
-	return nil
+	return im.in(ctx).userGetPosts(id, query)
 }
 
-func (im *InMemoryDB) Ping(ctx context.Context) error {
-	return InMemoryDBPingFn(ctx)
+func (im *InMemoryDB) UserDeleteByID(ctx context.Context, id uint64, opts models.DeleteOptions) error {
+	if InMemoryUserDeleteByIDFn != nil {
+		return InMemoryUserDeleteByIDFn(ctx, id, opts)
+	}
+
+	return im.in(ctx).userDeleteByID(id, opts)
 }
 
-func (im *InMemoryDB) UserCreate(ctx context.Context, user models.User) (*models.User, error) {
-	return InMemoryUserCreateFn(ctx, user)
+func (im *InMemoryDB) UserUpdate(ctx context.Context, user models.UserUpdate) (*models.User, error) {
+	if InMemoryUserUpdateFn != nil {
+		return InMemoryUserUpdateFn(ctx, user)
+	}
+
+	return im.in(ctx).userUpdate(user)
 }
 
-func (im *InMemoryDB) UserGetAll(ctx context.Context) ([]*models.User, error) {
-	return InMemoryUserGetAllFn(ctx)
+func (im *InMemoryDB) UserPatch(ctx context.Context, patch models.UserPatch) (*models.User, error) {
+	if InMemoryUserPatchFn != nil {
+		return InMemoryUserPatchFn(ctx, patch)
+	}
+
+	return im.in(ctx).userPatch(patch)
 }
 
-func (im *InMemoryDB) UserGetByID(ctx context.Context, id uint64) (*models.User, error) {
-	return InMemoryUserGetByIDFn(ctx, id)
+func (im *InMemoryDB) UserRestoreByID(ctx context.Context, id uint64) (*models.User, error) {
+	if InMemoryUserRestoreByIDFn != nil {
+		return InMemoryUserRestoreByIDFn(ctx, id)
+	}
+
+	return im.in(ctx).userRestoreByID(id)
 }
 
-func (im *InMemoryDB) UserDeleteByID(ctx context.Context, id uint64) error {
-	return InMemoryUserDeleteByIDFn(ctx, id)
+func (im *InMemoryDB) PostCreate(ctx context.Context, post models.Post) (*models.Post, error) {
+	if InMemoryPostCreateFn != nil {
+		return InMemoryPostCreateFn(ctx, post)
+	}
+
+	return im.in(ctx).postCreate(post)
 }
 
-func (im *InMemoryDB) UserUpdate(ctx context.Context, user models.UserUpdate) (*models.User, error) {
-	return InMemoryUserUpdateFn(ctx, user)
+func (im *InMemoryDB) PostCreateBulk(ctx context.Context, posts []models.Post, opts models.BulkOptions) ([]*models.Post, error) {
+	if InMemoryPostCreateBulkFn != nil {
+		return InMemoryPostCreateBulkFn(ctx, posts, opts)
+	}
+
+	return im.in(ctx).postCreateBulk(posts, opts)
 }
 
+func (im *InMemoryDB) PostGetAll(ctx context.Context, query models.PostQuery) (*models.Page[*models.Post], error) {
+	if InMemoryPostGetAllFn != nil {
+		return InMemoryPostGetAllFn(ctx, query)
+	}
 
-func (im *InMemoryDB) PostCreate(ctx context.Context, post models.Post) (*models.Post, error) {
-	return InMemoryPostCreateFn(ctx, post)
+	return im.in(ctx).postGetAll(query)
 }
 
-func (im *InMemoryDB) PostGetAll(ctx context.Context) ([]*models.Post, error) {
-	return InMemoryPostGetAllFn(ctx)
+func (im *InMemoryDB) PostGetByID(ctx context.Context, id uint64, include models.PostInclude) (*models.Post, error) {
+	if InMemoryPostGetByIDFn != nil {
+		return InMemoryPostGetByIDFn(ctx, id, include)
+	}
+
+	return im.in(ctx).postGetByID(id, include)
 }
 
-func (im *InMemoryDB) PostGetByID(ctx context.Context, id uint64) (*models.Post, error) {
-	return InMemoryPostGetByIDFn(ctx, id)
+func (im *InMemoryDB) PostDeleteByID(ctx context.Context, id uint64, opts models.DeleteOptions) error {
+	if InMemoryPostDeleteByIDFn != nil {
+		return InMemoryPostDeleteByIDFn(ctx, id, opts)
+	}
+
+	return im.in(ctx).postDeleteByID(id, opts)
 }
 
-func (im *InMemoryDB) PostDeleteByID(ctx context.Context, id uint64) error {
-	return InMemoryPostDeleteByIDFn(ctx, id)
+func (im *InMemoryDB) PostDeleteByUserID(ctx context.Context, userID uint64) (int, error) {
+	if InMemoryPostDeleteByUserIDFn != nil {
+		return InMemoryPostDeleteByUserIDFn(ctx, userID)
+	}
+
+	return im.in(ctx).postDeleteByUserID(userID)
 }
 
 func (im *InMemoryDB) PostUpdate(ctx context.Context, post models.PostUpdate) (*models.Post, error) {
-	return InMemoryPostUpdateFn(ctx, post)
+	if InMemoryPostUpdateFn != nil {
+		return InMemoryPostUpdateFn(ctx, post)
+	}
+
+	return im.in(ctx).postUpdate(post)
+}
+
+func (im *InMemoryDB) PostPatch(ctx context.Context, patch models.PostPatch) (*models.Post, error) {
+	if InMemoryPostPatchFn != nil {
+		return InMemoryPostPatchFn(ctx, patch)
+	}
+
+	return im.in(ctx).postPatch(patch)
+}
+
+func (im *InMemoryDB) PostRestoreByID(ctx context.Context, id uint64) (*models.Post, error) {
+	if InMemoryPostRestoreByIDFn != nil {
+		return InMemoryPostRestoreByIDFn(ctx, id)
+	}
+
+	return im.in(ctx).postRestoreByID(id)
+}
+
+func (im *InMemoryDB) IdempotencyKeyClaim(ctx context.Context, record models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	if InMemoryIdempotencyKeyClaimFn != nil {
+		return InMemoryIdempotencyKeyClaimFn(ctx, record)
+	}
+
+	return im.in(ctx).idempotencyKeyClaim(record)
+}
+
+func (im *InMemoryDB) IdempotencyKeyComplete(ctx context.Context, record models.IdempotencyRecord) error {
+	if InMemoryIdempotencyKeyCompleteFn != nil {
+		return InMemoryIdempotencyKeyCompleteFn(ctx, record)
+	}
+
+	return im.in(ctx).idempotencyKeyComplete(record)
+}
+
+func (im *InMemoryDB) IdempotencyKeyRelease(ctx context.Context, key string) error {
+	if InMemoryIdempotencyKeyReleaseFn != nil {
+		return InMemoryIdempotencyKeyReleaseFn(ctx, key)
+	}
+
+	return im.in(ctx).idempotencyKeyRelease(key)
 }
+
+type ctxKeyTx struct{}
+
+// Repository to serve the calls made with `ctx` from, the one of its
+// transaction on this store if any
+func (im *InMemoryDB) in(ctx context.Context) *InMemoryDB {
+	if tx, ok := ctx.Value(ctxKeyTx{}).(*InMemoryDB); ok && tx.tables == im.tables {
+		return tx
+	}
+
+	return im
+}
+
+// Copy of the rows, their pointer fields are reassigned and never modified
+// so copying the structs is enough
+func (t *tables) clone() *tables {
+	result := &tables{
+		users: make(map[uint64]*userRow, len(t.users)),
+		posts: make(map[uint64]*postRow, len(t.posts)),
+		// Records are replaced rather than modified, they can be shared
+		idempotencyKeys: maps.Clone(t.idempotencyKeys),
+		lastUserID:      t.lastUserID,
+		lastPostID:      t.lastPostID,
+	}
+	for id, row := range t.users {
+		clone := *row
+		result.users[id] = &clone
+	}
+	for id, row := range t.posts {
+		clone := *row
+		result.posts[id] = &clone
+	}
+
+	return result
+}
+
+type noLock struct{}
+
+func (noLock) Lock()    {}
+func (noLock) Unlock()  {}
+func (noLock) RLock()   {}
+func (noLock) RUnlock() {}
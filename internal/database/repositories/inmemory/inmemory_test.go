@@ -0,0 +1,280 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/databasetest"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
+)
+
+// Repository whose clock moves a second forward on every write
+func newTestDB() *InMemoryDB {
+	now := time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	return New(func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	})
+}
+
+func createUserWithPosts(t *testing.T, db *InMemoryDB, email string, posts int) *models.User {
+	t.Helper()
+
+	u, err := db.UserCreate(context.Background(), models.User{Name: "John Doe", Email: email})
+	require.NoError(t, err)
+
+	for range posts {
+		_, err := db.PostCreate(context.Background(), models.Post{Title: "coolio", Content: "coolest content", UserID: u.ID})
+		require.NoError(t, err)
+	}
+
+	return u
+}
+
+func countPosts(t *testing.T, db *InMemoryDB, userID uint64) int {
+	t.Helper()
+
+	page, err := db.PostGetAll(context.Background(), models.PostQuery{
+		Pagination: models.Pagination{Limit: 100},
+		UserID:     &userID,
+	})
+	require.NoError(t, err)
+
+	return len(page.Items)
+}
+
+func Test_InMemoryDB_Users(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should assign increasing IDs", func(t *testing.T) {
+		db := newTestDB()
+
+		first := createUserWithPosts(t, db, "johnnydoe@gmail.com", 0)
+		second := createUserWithPosts(t, db, "janedoe@gmail.com", 0)
+
+		assert.Equal(t, uint64(1), first.ID)
+		assert.Equal(t, uint64(2), second.ID)
+		assert.Equal(t, first.CreatedAt, first.UpdatedAt)
+	})
+
+	t.Run("should keep emails unique", func(t *testing.T) {
+		db := newTestDB()
+		createUserWithPosts(t, db, "johnnydoe@gmail.com", 0)
+		jane := createUserWithPosts(t, db, "janedoe@gmail.com", 0)
+		taken := "johnnydoe@gmail.com"
+
+		_, createErr := db.UserCreate(ctx, models.User{Name: "Johnny", Email: taken})
+		_, patchErr := db.UserPatch(ctx, models.UserPatch{ID: &jane.ID, Email: &taken})
+
+		var conflict *database.ConflictError
+		require.ErrorAs(t, createErr, &conflict)
+		assert.Equal(t, "email", conflict.Field)
+		assert.ErrorIs(t, patchErr, database.ErrConflict)
+	})
+
+	t.Run("should let users keep their own email", func(t *testing.T) {
+		db := newTestDB()
+		u := createUserWithPosts(t, db, "johnnydoe@gmail.com", 0)
+
+		updated, err := db.UserUpdate(ctx, models.UserUpdate{ID: &u.ID, Name: "Johnny", Email: u.Email})
+
+		require.NoError(t, err)
+		assert.Equal(t, "Johnny", updated.Name)
+		assert.True(t, updated.UpdatedAt.After(u.UpdatedAt))
+	})
+
+	t.Run("should return not found for missing users", func(t *testing.T) {
+		db := newTestDB()
+		id := uint64(1)
+
+		_, getErr := db.UserGetByID(ctx, id, models.UserInclude{})
+		_, patchErr := db.UserPatch(ctx, models.UserPatch{ID: &id})
+		deleteErr := db.UserDeleteByID(ctx, id, models.DeleteOptions{})
+
+		assert.ErrorIs(t, getErr, database.ErrNotFound)
+		assert.ErrorIs(t, patchErr, database.ErrNotFound)
+		assert.ErrorIs(t, deleteErr, database.ErrNotFound)
+	})
+
+	t.Run("should only write when updated_at matches", func(t *testing.T) {
+		db := newTestDB()
+		u := createUserWithPosts(t, db, "johnnydoe@gmail.com", 0)
+		stale := u.UpdatedAt.Add(-time.Second)
+		name := "Johnny"
+
+		_, staleErr := db.UserPatch(ctx, models.UserPatch{ID: &u.ID, Name: &name, IfUpdatedAt: &stale})
+		patched, err := db.UserPatch(ctx, models.UserPatch{ID: &u.ID, Name: &name, IfUpdatedAt: &u.UpdatedAt})
+
+		assert.ErrorIs(t, staleErr, database.ErrPreconditionFailed)
+		require.NoError(t, err)
+		assert.Equal(t, name, patched.Name)
+	})
+
+	t.Run("should refuse to delete a user with posts without cascade", func(t *testing.T) {
+		db := newTestDB()
+		u := createUserWithPosts(t, db, "johnnydoe@gmail.com", 2)
+
+		err := db.UserDeleteByID(ctx, u.ID, models.DeleteOptions{})
+
+		var hasPosts *database.UserHasPostsError
+		require.ErrorAs(t, err, &hasPosts)
+		assert.Equal(t, 2, hasPosts.Posts)
+		assert.Equal(t, 2, countPosts(t, db, u.ID))
+	})
+
+	t.Run("should restore the posts deleted along with the user", func(t *testing.T) {
+		db := newTestDB()
+		u := createUserWithPosts(t, db, "johnnydoe@gmail.com", 3)
+		// Deleted on its own, before the user
+		require.NoError(t, db.PostDeleteByID(ctx, 1, models.DeleteOptions{}))
+		require.NoError(t, db.UserDeleteByID(ctx, u.ID, models.DeleteOptions{Cascade: true}))
+
+		_, getErr := db.UserGetByID(ctx, u.ID, models.UserInclude{})
+		deletedPosts := countPosts(t, db, u.ID)
+		restored, err := db.UserRestoreByID(ctx, u.ID)
+
+		assert.ErrorIs(t, getErr, database.ErrNotFound)
+		assert.Equal(t, 0, deletedPosts)
+		require.NoError(t, err)
+		assert.Equal(t, u.ID, restored.ID)
+		assert.Equal(t, 2, countPosts(t, db, u.ID))
+	})
+
+	t.Run("should embed the live posts of the user", func(t *testing.T) {
+		db := newTestDB()
+		u := createUserWithPosts(t, db, "johnnydoe@gmail.com", 2)
+		createUserWithPosts(t, db, "janedoe@gmail.com", 0)
+		require.NoError(t, db.PostDeleteByID(ctx, 2, models.DeleteOptions{}))
+
+		withPosts, err := db.UserGetByID(ctx, u.ID, models.UserInclude{Posts: true})
+		page, listErr := db.UserGetAll(ctx, models.UserQuery{
+			Pagination: models.Pagination{Limit: 10},
+			Include:    models.UserInclude{Posts: true},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, withPosts.Posts, 1)
+		assert.Equal(t, uint64(1), withPosts.Posts[0].ID)
+		require.NoError(t, listErr)
+		require.Len(t, page.Items, 2)
+		assert.Empty(t, page.Items[1].Posts)
+		assert.NotNil(t, page.Items[1].Posts)
+	})
+}
+
+func Test_InMemoryDB_Posts(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should require an existing user", func(t *testing.T) {
+		db := newTestDB()
+		u := createUserWithPosts(t, db, "johnnydoe@gmail.com", 0)
+		require.NoError(t, db.UserDeleteByID(ctx, u.ID, models.DeleteOptions{}))
+
+		_, missingErr := db.PostCreate(ctx, models.Post{Title: "coolio", Content: "coolest content", UserID: 42})
+		_, deletedErr := db.PostCreate(ctx, models.Post{Title: "coolio", Content: "coolest content", UserID: u.ID})
+
+		assert.ErrorIs(t, missingErr, database.ErrForeignKey)
+		assert.ErrorIs(t, deletedErr, database.ErrUserDeleted)
+	})
+
+	t.Run("should not restore a post whose user is deleted", func(t *testing.T) {
+		db := newTestDB()
+		u := createUserWithPosts(t, db, "johnnydoe@gmail.com", 1)
+		require.NoError(t, db.UserDeleteByID(ctx, u.ID, models.DeleteOptions{Cascade: true}))
+
+		_, err := db.PostRestoreByID(ctx, 1)
+
+		assert.ErrorIs(t, err, database.ErrUserDeleted)
+	})
+
+	t.Run("should return copies of the stored posts", func(t *testing.T) {
+		db := newTestDB()
+		createUserWithPosts(t, db, "johnnydoe@gmail.com", 1)
+
+		p, err := db.PostGetByID(ctx, 1, models.PostInclude{User: true})
+		require.NoError(t, err)
+		p.Title = "changed"
+		p.User.Name = "changed"
+
+		stored, err := db.PostGetByID(ctx, 1, models.PostInclude{User: true})
+		require.NoError(t, err)
+		assert.Equal(t, "coolio", stored.Title)
+		assert.Equal(t, "John Doe", stored.User.Name)
+	})
+
+	t.Run("should filter, sort and paginate posts", func(t *testing.T) {
+		db := newTestDB()
+		u := createUserWithPosts(t, db, "johnnydoe@gmail.com", 0)
+		for _, title := range []string{"b", "A go post", "c", "another go post"} {
+			_, err := db.PostCreate(ctx, models.Post{Title: title, Content: "coolest content", UserID: u.ID})
+			require.NoError(t, err)
+		}
+
+		query := models.PostQuery{
+			Pagination:    models.Pagination{Limit: 1},
+			TitleContains: "GO",
+			Sort:          []models.SortField{{Field: models.PostFieldCreatedAt, Desc: true}},
+		}
+		first, err := db.PostGetAll(ctx, query)
+		require.NoError(t, err)
+		query.After = first.Next
+		second, err := db.PostGetAll(ctx, query)
+		require.NoError(t, err)
+
+		require.Len(t, first.Items, 1)
+		assert.Equal(t, "another go post", first.Items[0].Title)
+		require.NotNil(t, first.Next)
+		require.Len(t, second.Items, 1)
+		assert.Equal(t, "A go post", second.Items[0].Title)
+		assert.Nil(t, second.Next)
+	})
+}
+
+func Test_InMemoryDB_Concurrency(t *testing.T) {
+	db := newTestDB()
+	u := createUserWithPosts(t, db, "johnnydoe@gmail.com", 0)
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.PostCreate(context.Background(), models.Post{Title: "coolio", Content: "coolest content", UserID: u.ID})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 50, countPosts(t, db, u.ID))
+}
+
+func Test_InMemoryDB_Hooks(t *testing.T) {
+	db := newTestDB()
+	createUserWithPosts(t, db, "johnnydoe@gmail.com", 0)
+
+	oldUserGetByIDFn := InMemoryUserGetByIDFn
+	defer func() {
+		InMemoryUserGetByIDFn = oldUserGetByIDFn
+	}()
+	InMemoryUserGetByIDFn = func(ctx context.Context, id uint64, include models.UserInclude) (*models.User, error) {
+		return nil, errors.New("You've met a terrible fate, haven't you?")
+	}
+
+	_, err := db.UserGetByID(context.Background(), 1, models.UserInclude{})
+
+	assert.EqualError(t, err, "You've met a terrible fate, haven't you?")
+}
+
+func Test_InMemoryDB_Contract(t *testing.T) {
+	databasetest.RunContract(t, func(t *testing.T) database.DBRepository {
+		return New(time.Now)
+	})
+}
@@ -0,0 +1,686 @@
+package inmemory
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
+)
+
+// Rows hold the resources without their embedded relations, which are added
+// when reading them
+type userRow struct {
+	models.User
+	DeletedAt *time.Time
+}
+
+type postRow struct {
+	models.Post
+	DeletedAt *time.Time
+}
+
+// USER
+func (im *InMemoryDB) userCreate(user models.User) (*models.User, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	// Deleted users keep their email until purged, as in Postgres
+	if im.emailTaken(user.Email, 0) {
+		return nil, &database.ConflictError{Field: "email"}
+	}
+
+	now := im.timestamp()
+	im.lastUserID++
+	row := &userRow{
+		User: models.User{
+			ID:        im.lastUserID,
+			Name:      user.Name,
+			Email:     user.Email,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+	im.users[row.ID] = row
+
+	return im.toUser(row, false), nil
+}
+
+func (im *InMemoryDB) userCreateBulk(users []models.User, opts models.BulkOptions) ([]*models.User, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	failed := map[int]error{}
+	seen := map[string]bool{}
+	for i, u := range users {
+		if seen[u.Email] || im.emailTaken(u.Email, 0) {
+			failed[i] = &database.ConflictError{Field: "email"}
+		}
+		seen[u.Email] = true
+	}
+
+	created := make([]*models.User, len(users))
+	if len(failed) > 0 && !opts.Partial {
+		return created, &database.BulkError{Errors: failed}
+	}
+
+	now := im.timestamp()
+	for i, u := range users {
+		if failed[i] != nil {
+			continue
+		}
+
+		im.lastUserID++
+		row := &userRow{
+			User: models.User{
+				ID:        im.lastUserID,
+				Name:      u.Name,
+				Email:     u.Email,
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+		}
+		im.users[row.ID] = row
+		created[i] = im.toUser(row, false)
+	}
+
+	if len(failed) > 0 {
+		return created, &database.BulkError{Errors: failed}
+	}
+
+	return created, nil
+}
+
+func (im *InMemoryDB) userGetAll(query models.UserQuery) (*models.Page[*models.User], error) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	rows := make([]*userRow, 0, len(im.users))
+	for _, row := range im.users {
+		if row.DeletedAt == nil && (query.After == nil || row.ID > query.After.ID) {
+			rows = append(rows, row)
+		}
+	}
+	slices.SortFunc(rows, func(a, b *userRow) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(rows) > query.Limit+1 {
+		rows = rows[:query.Limit+1]
+	}
+
+	users := make([]*models.User, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, im.toUser(row, query.Include.Posts))
+	}
+
+	return models.NewPage(users, query.Limit, func(u *models.User) *models.Cursor {
+		return &models.Cursor{ID: u.ID}
+	}), nil
+}
+
+func (im *InMemoryDB) userGetByID(id uint64, include models.UserInclude) (*models.User, error) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	row, err := im.liveUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return im.toUser(row, include.Posts), nil
+}
+
+func (im *InMemoryDB) userGetPosts(id uint64, query models.PostQuery) (*models.Page[*models.Post], error) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	if _, err := im.liveUser(id); err != nil {
+		return nil, err
+	}
+
+	query.UserID = &id
+
+	return im.postPage(query)
+}
+
+// Soft deletes the user, and with `opts.Cascade` its posts at the same
+// instant, so restoring the user can tell which posts to bring back
+func (im *InMemoryDB) userDeleteByID(id uint64, opts models.DeleteOptions) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	row, err := im.liveUser(id)
+	if err != nil {
+		return err
+	}
+	if err := checkUpdatedAt(row.UpdatedAt, opts.IfUpdatedAt); err != nil {
+		return err
+	}
+
+	posts := im.livePostsOf(id)
+	if len(posts) > 0 && !opts.Cascade {
+		return &database.UserHasPostsError{Posts: len(posts)}
+	}
+
+	// Bumps updated_at like any other write, so ETags taken before no longer
+	// match
+	deletedAt := im.timestamp()
+	row.DeletedAt = &deletedAt
+	row.UpdatedAt = deletedAt
+	for _, p := range posts {
+		p.DeletedAt = &deletedAt
+		p.UpdatedAt = deletedAt
+	}
+
+	return nil
+}
+
+func (im *InMemoryDB) userUpdate(update models.UserUpdate) (*models.User, error) {
+	return im.userPatch(models.UserPatch{
+		ID:          update.ID,
+		Name:        &update.Name,
+		Email:       &update.Email,
+		IfUpdatedAt: update.IfUpdatedAt,
+	})
+}
+
+func (im *InMemoryDB) userPatch(patch models.UserPatch) (*models.User, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	row, err := im.liveUser(*patch.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUpdatedAt(row.UpdatedAt, patch.IfUpdatedAt); err != nil {
+		return nil, err
+	}
+	if patch.Email != nil && im.emailTaken(*patch.Email, row.ID) {
+		return nil, &database.ConflictError{Field: "email"}
+	}
+
+	if patch.Name != nil {
+		row.Name = *patch.Name
+	}
+	if patch.Email != nil {
+		row.Email = *patch.Email
+	}
+	row.UpdatedAt = im.timestamp()
+
+	return im.toUser(row, false), nil
+}
+
+func (im *InMemoryDB) userRestoreByID(id uint64) (*models.User, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	row, ok := im.users[id]
+	if !ok {
+		return nil, database.ErrNotFound
+	}
+	// Restoring a user that is not deleted is a no-op
+	if row.DeletedAt == nil {
+		return im.toUser(row, false), nil
+	}
+
+	now := im.timestamp()
+	for _, p := range im.posts {
+		if p.UserID == id && p.DeletedAt != nil && p.DeletedAt.Equal(*row.DeletedAt) {
+			p.DeletedAt = nil
+			p.UpdatedAt = now
+		}
+	}
+	row.DeletedAt = nil
+	row.UpdatedAt = now
+
+	return im.toUser(row, false), nil
+}
+
+// POST
+func (im *InMemoryDB) postCreate(post models.Post) (*models.Post, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	u, ok := im.users[post.UserID]
+	if !ok {
+		return nil, database.ErrForeignKey
+	}
+	if u.DeletedAt != nil {
+		return nil, database.ErrUserDeleted
+	}
+
+	now := im.timestamp()
+	im.lastPostID++
+	row := &postRow{
+		Post: models.Post{
+			ID:        im.lastPostID,
+			Title:     post.Title,
+			Content:   post.Content,
+			UserID:    post.UserID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+	im.posts[row.ID] = row
+
+	return im.toPost(row, false), nil
+}
+
+func (im *InMemoryDB) postCreateBulk(posts []models.Post, opts models.BulkOptions) ([]*models.Post, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	failed := map[int]error{}
+	for i, p := range posts {
+		u, ok := im.users[p.UserID]
+		switch {
+		case !ok:
+			failed[i] = database.ErrForeignKey
+		case u.DeletedAt != nil:
+			failed[i] = database.ErrUserDeleted
+		}
+	}
+
+	created := make([]*models.Post, len(posts))
+	if len(failed) > 0 && !opts.Partial {
+		return created, &database.BulkError{Errors: failed}
+	}
+
+	now := im.timestamp()
+	for i, p := range posts {
+		if failed[i] != nil {
+			continue
+		}
+
+		im.lastPostID++
+		row := &postRow{
+			Post: models.Post{
+				ID:        im.lastPostID,
+				Title:     p.Title,
+				Content:   p.Content,
+				UserID:    p.UserID,
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+		}
+		im.posts[row.ID] = row
+		created[i] = im.toPost(row, false)
+	}
+
+	if len(failed) > 0 {
+		return created, &database.BulkError{Errors: failed}
+	}
+
+	return created, nil
+}
+
+func (im *InMemoryDB) postGetAll(query models.PostQuery) (*models.Page[*models.Post], error) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	return im.postPage(query)
+}
+
+func (im *InMemoryDB) postGetByID(id uint64, include models.PostInclude) (*models.Post, error) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	row, err := im.livePost(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return im.toPost(row, include.User), nil
+}
+
+func (im *InMemoryDB) postDeleteByID(id uint64, opts models.DeleteOptions) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	row, err := im.livePost(id)
+	if err != nil {
+		return err
+	}
+	if err := checkUpdatedAt(row.UpdatedAt, opts.IfUpdatedAt); err != nil {
+		return err
+	}
+
+	deletedAt := im.timestamp()
+	row.DeletedAt = &deletedAt
+	row.UpdatedAt = deletedAt
+
+	return nil
+}
+
+func (im *InMemoryDB) postDeleteByUserID(userID uint64) (int, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	if _, err := im.liveUser(userID); err != nil {
+		return 0, err
+	}
+
+	deletedAt := im.timestamp()
+	rows := im.livePostsOf(userID)
+	for _, row := range rows {
+		row.DeletedAt = &deletedAt
+		row.UpdatedAt = deletedAt
+	}
+
+	return len(rows), nil
+}
+
+func (im *InMemoryDB) postUpdate(update models.PostUpdate) (*models.Post, error) {
+	return im.postPatch(models.PostPatch{
+		ID:          update.ID,
+		Title:       &update.Title,
+		Content:     &update.Content,
+		IfUpdatedAt: update.IfUpdatedAt,
+	})
+}
+
+func (im *InMemoryDB) postPatch(patch models.PostPatch) (*models.Post, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	row, err := im.livePost(*patch.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUpdatedAt(row.UpdatedAt, patch.IfUpdatedAt); err != nil {
+		return nil, err
+	}
+
+	if patch.Title != nil {
+		row.Title = *patch.Title
+	}
+	if patch.Content != nil {
+		row.Content = *patch.Content
+	}
+	row.UpdatedAt = im.timestamp()
+
+	return im.toPost(row, false), nil
+}
+
+// Posts of a deleted user stay deleted until the user is restored
+func (im *InMemoryDB) postRestoreByID(id uint64) (*models.Post, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	row, ok := im.posts[id]
+	if !ok {
+		return nil, database.ErrNotFound
+	}
+	if row.DeletedAt == nil {
+		return im.toPost(row, false), nil
+	}
+	if im.users[row.UserID].DeletedAt != nil {
+		return nil, database.ErrUserDeleted
+	}
+
+	row.DeletedAt = nil
+	row.UpdatedAt = im.timestamp()
+
+	return im.toPost(row, false), nil
+}
+
+// Applies the filters, ordering and pagination of `query` over the live posts
+func (im *InMemoryDB) postPage(query models.PostQuery) (*models.Page[*models.Post], error) {
+	sort := models.WithIDTiebreaker(query.Sort)
+
+	var after *postRow
+	if query.After != nil {
+		row, err := cursorRow(query.After)
+		if err != nil {
+			return nil, err
+		}
+		after = row
+	}
+
+	rows := make([]*postRow, 0, len(im.posts))
+	for _, row := range im.posts {
+		if row.DeletedAt != nil || !matches(row, query) {
+			continue
+		}
+		if after != nil && comparePosts(sort, row, after) <= 0 {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	slices.SortFunc(rows, func(a, b *postRow) int {
+		return comparePosts(sort, a, b)
+	})
+	if len(rows) > query.Limit+1 {
+		rows = rows[:query.Limit+1]
+	}
+
+	posts := make([]*models.Post, 0, len(rows))
+	for _, row := range rows {
+		posts = append(posts, im.toPost(row, query.Include.User))
+	}
+
+	return models.NewPage(posts, query.Limit, func(p *models.Post) *models.Cursor {
+		return postCursor(p, sort)
+	}), nil
+}
+
+func matches(row *postRow, query models.PostQuery) bool {
+	if query.UserID != nil && row.UserID != *query.UserID {
+		return false
+	}
+	if query.CreatedAfter != nil && !row.CreatedAt.After(*query.CreatedAfter) {
+		return false
+	}
+	if query.CreatedBefore != nil && !row.CreatedAt.Before(*query.CreatedBefore) {
+		return false
+	}
+	if query.TitleContains != "" && !strings.Contains(strings.ToLower(row.Title), strings.ToLower(query.TitleContains)) {
+		return false
+	}
+
+	return true
+}
+
+// Orders `a` and `b` by the sort fields, in order
+func comparePosts(sort []models.SortField, a *postRow, b *postRow) int {
+	for _, sf := range sort {
+		var c int
+		switch sf.Field {
+		case models.PostFieldID:
+			c = cmp.Compare(a.ID, b.ID)
+		case models.PostFieldTitle:
+			c = strings.Compare(a.Title, b.Title)
+		case models.PostFieldCreatedAt:
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		case models.PostFieldUpdatedAt:
+			c = a.UpdatedAt.Compare(b.UpdatedAt)
+		}
+		if sf.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// Position of the post in the given ordering, same format as Postgres
+func postCursor(p *models.Post, sort []models.SortField) *models.Cursor {
+	cursor := &models.Cursor{
+		ID:   p.ID,
+		Keys: map[string]string{},
+	}
+
+	for _, sf := range sort {
+		switch sf.Field {
+		case models.PostFieldTitle:
+			cursor.Keys[sf.Field] = p.Title
+		case models.PostFieldCreatedAt:
+			cursor.Keys[sf.Field] = p.CreatedAt.Format(time.RFC3339Nano)
+		case models.PostFieldUpdatedAt:
+			cursor.Keys[sf.Field] = p.UpdatedAt.Format(time.RFC3339Nano)
+		}
+	}
+
+	return cursor
+}
+
+// Post placed where the cursor points, to compare the other posts against
+func cursorRow(cursor *models.Cursor) (*postRow, error) {
+	row := &postRow{Post: models.Post{ID: cursor.ID}}
+
+	for field, raw := range cursor.Keys {
+		switch field {
+		case models.PostFieldTitle:
+			row.Title = raw
+		case models.PostFieldCreatedAt, models.PostFieldUpdatedAt:
+			t, err := time.Parse(time.RFC3339Nano, raw)
+			if err != nil {
+				return nil, err
+			}
+			if field == models.PostFieldCreatedAt {
+				row.CreatedAt = t
+			} else {
+				row.UpdatedAt = t
+			}
+		default:
+			return nil, fmt.Errorf("unknown cursor key %q", field)
+		}
+	}
+
+	return row, nil
+}
+
+// IDEMPOTENCY
+func (im *InMemoryDB) idempotencyKeyClaim(record models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	if held, ok := im.idempotencyKeys[record.Key]; ok && held.ExpiresAt.After(im.now()) {
+		result := *held
+		result.Header = held.Header.Clone()
+		result.Body = slices.Clone(held.Body)
+
+		return &result, nil
+	}
+
+	record.Status = 0
+	record.Header = nil
+	record.Body = nil
+	im.idempotencyKeys[record.Key] = &record
+
+	return nil, nil
+}
+
+func (im *InMemoryDB) idempotencyKeyComplete(record models.IdempotencyRecord) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	held, ok := im.idempotencyKeys[record.Key]
+	if !ok {
+		return database.ErrNotFound
+	}
+
+	completed := *held
+	completed.Status = record.Status
+	completed.Header = record.Header.Clone()
+	completed.Body = slices.Clone(record.Body)
+	im.idempotencyKeys[record.Key] = &completed
+
+	return nil
+}
+
+func (im *InMemoryDB) idempotencyKeyRelease(key string) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	delete(im.idempotencyKeys, key)
+
+	return nil
+}
+
+// HELPERS, callers must hold the lock
+func (im *InMemoryDB) liveUser(id uint64) (*userRow, error) {
+	row, ok := im.users[id]
+	if !ok || row.DeletedAt != nil {
+		return nil, database.ErrNotFound
+	}
+
+	return row, nil
+}
+
+func (im *InMemoryDB) livePost(id uint64) (*postRow, error) {
+	row, ok := im.posts[id]
+	if !ok || row.DeletedAt != nil {
+		return nil, database.ErrNotFound
+	}
+
+	return row, nil
+}
+
+// Live posts of the user, ordered by ID
+func (im *InMemoryDB) livePostsOf(userID uint64) []*postRow {
+	rows := []*postRow{}
+	for _, row := range im.posts {
+		if row.UserID == userID && row.DeletedAt == nil {
+			rows = append(rows, row)
+		}
+	}
+	slices.SortFunc(rows, func(a, b *postRow) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return rows
+}
+
+// Whether a user other than `except` has the email
+func (im *InMemoryDB) emailTaken(email string, except uint64) bool {
+	for _, row := range im.users {
+		if row.ID != except && row.Email == email {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Microsecond precision, like Postgres, so ETags survive a switch of driver
+func (im *InMemoryDB) timestamp() time.Time {
+	return im.now().UTC().Truncate(time.Microsecond)
+}
+
+func checkUpdatedAt(updatedAt time.Time, ifUpdatedAt *time.Time) error {
+	if ifUpdatedAt != nil && !updatedAt.Equal(*ifUpdatedAt) {
+		return database.ErrPreconditionFailed
+	}
+
+	return nil
+}
+
+// MAPPING, to copies so callers can't modify the stored rows. Callers must
+// hold the lock.
+func (im *InMemoryDB) toUser(row *userRow, withPosts bool) *models.User {
+	u := row.User
+	if withPosts {
+		u.Posts = make([]*models.Post, 0)
+		for _, p := range im.livePostsOf(row.ID) {
+			u.Posts = append(u.Posts, im.toPost(p, false))
+		}
+	}
+
+	return &u
+}
+
+func (im *InMemoryDB) toPost(row *postRow, withUser bool) *models.Post {
+	p := row.Post
+	if withUser {
+		p.User = im.toUser(im.users[row.UserID], false)
+	}
+
+	return &p
+}
@@ -12,6 +12,7 @@ import (
 	"entgo.io/ent"
 	"entgo.io/ent/dialect/sql"
 	"entgo.io/ent/dialect/sql/sqlgraph"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent/idempotencykey"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent/post"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent/user"
 )
@@ -74,8 +75,9 @@ var (
 func checkColumn(table, column string) error {
 	initCheck.Do(func() {
 		columnCheck = sql.NewColumnCheck(map[string]func(string) bool{
-			post.Table: post.ValidColumn,
-			user.Table: user.ValidColumn,
+			idempotencykey.Table: idempotencykey.ValidColumn,
+			post.Table:           post.ValidColumn,
+			user.Table:           user.ValidColumn,
 		})
 	})
 	return columnCheck(table, column)
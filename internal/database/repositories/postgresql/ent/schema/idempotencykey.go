@@ -0,0 +1,47 @@
+package schema
+
+import (
+	"net/http"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+)
+
+// IdempotencyKey holds the response to a request sent with an
+// `Idempotency-Key` header, replayed to its retries until it expires.
+type IdempotencyKey struct {
+	ent.Schema
+}
+
+// Fields of the IdempotencyKey.
+func (IdempotencyKey) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("key").
+			NotEmpty().
+			Unique().
+			Immutable(),
+		// Hash of the request, its retries must have the same
+		field.String("fingerprint").
+			Immutable(),
+		// Zero while the request is being processed
+		field.Int("status").
+			Default(0),
+		field.JSON("header", http.Header{}).
+			Optional(),
+		field.Bytes("body").
+			Optional(),
+		field.Time("created_at").
+			Default(now).
+			Immutable(),
+		field.Time("expires_at"),
+	}
+}
+
+// Indexes of the IdempotencyKey.
+func (IdempotencyKey) Indexes() []ent.Index {
+	return []ent.Index{
+		// Expired keys are purged
+		index.Fields("expires_at"),
+	}
+}
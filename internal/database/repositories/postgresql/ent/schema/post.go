@@ -4,7 +4,6 @@ import (
 	"entgo.io/ent"
 	"entgo.io/ent/schema/edge"
 	"entgo.io/ent/schema/field"
-	"time"
 )
 
 // Post holds the schema definition for the Post entity.
@@ -21,14 +20,18 @@ func (Post) Fields() []ent.Field {
 		field.String("content").
 			NotEmpty(),
 		field.Time("created_at").
-			Default(time.Now).
+			Default(now).
 			Immutable(),
 		field.Uint64("user_id").
 			Positive().
 			Immutable(),
 		field.Time("updated_at").
-			Default(time.Now).
-			UpdateDefault(time.Now),
+			Default(now).
+			UpdateDefault(now),
+		// Soft deleted rows are hidden by `postgresql.softDeleteInterceptor`
+		field.Time("deleted_at").
+			Optional().
+			Nillable(),
 	}
 }
 
@@ -0,0 +1,10 @@
+package schema
+
+import "time"
+
+// Postgres keeps timestamps with microsecond precision, truncating them here
+// makes the values returned after a write equal to the stored ones. SQLite
+// keeps them as text, in UTC they compare the same way as in Postgres.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
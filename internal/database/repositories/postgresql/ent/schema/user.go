@@ -4,7 +4,6 @@ import (
 	"entgo.io/ent"
 	"entgo.io/ent/schema/edge"
 	"entgo.io/ent/schema/field"
-	"time"
 )
 
 // User holds the schema definition for the User entity.
@@ -22,11 +21,15 @@ func (User) Fields() []ent.Field {
 			Unique().
 			NotEmpty(),
 		field.Time("created_at").
-			Default(time.Now).
+			Default(now).
 			Immutable(),
 		field.Time("updated_at").
-			Default(time.Now).
-			UpdateDefault(time.Now),
+			Default(now).
+			UpdateDefault(now),
+		// Soft deleted rows are hidden by `postgresql.softDeleteInterceptor`
+		field.Time("deleted_at").
+			Optional().
+			Nillable(),
 	}
 }
 
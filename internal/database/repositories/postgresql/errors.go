@@ -0,0 +1,60 @@
+package postgresql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"entgo.io/ent/dialect/sql/sqlgraph"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent"
+)
+
+// Translates the errors of ent and the driver into the ones of the `database`
+// package, keeping the original as the cause. Errors that already belong to
+// it are returned as is.
+func translateError(err error) error {
+	var hasPosts *database.UserHasPostsError
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, database.ErrNotFound),
+		errors.Is(err, database.ErrPreconditionFailed),
+		errors.Is(err, database.ErrUserDeleted),
+		errors.As(err, &hasPosts):
+		return err
+	case ent.IsNotFound(err):
+		return fmt.Errorf("%w: %w", database.ErrNotFound, err)
+	case sqlgraph.IsForeignKeyConstraintError(err):
+		return fmt.Errorf("%w: %w", database.ErrForeignKey, err)
+	case ent.IsConstraintError(err):
+		return &database.ConflictError{Field: conflictField(err), Err: err}
+	default:
+		return fmt.Errorf("%w: %w", database.ErrUnavailable, err)
+	}
+}
+
+// Field behind a unique constraint violation, empty when it can't be told
+func conflictField(err error) string {
+	// Postgres names the constraint of a unique column `<table>_<column>_key`
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		field := strings.TrimPrefix(pgErr.ConstraintName, pgErr.TableName+"_")
+		return strings.TrimSuffix(field, "_key")
+	}
+
+	// SQLite reports `UNIQUE constraint failed: <table>.<column>`
+	_, column, found := strings.Cut(err.Error(), "UNIQUE constraint failed: ")
+	if !found {
+		return ""
+	}
+	if end := strings.IndexAny(column, ", "); end >= 0 {
+		column = column[:end]
+	}
+	_, column, _ = strings.Cut(column, ".")
+
+	return column
+}
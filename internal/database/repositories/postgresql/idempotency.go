@@ -0,0 +1,151 @@
+package postgresql
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent/idempotencykey"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/logger"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
+)
+
+func (pg *PostgresqlClient) IdempotencyKeyClaim(ctx context.Context, record models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	log := logger.
+		FromContext(ctx).
+		With().
+		Str("method", "postgresql.IdempotencyKeyClaim").
+		Logger()
+
+	var held *models.IdempotencyRecord
+	err := pg.WithTx(ctx, func(ctx context.Context, _ database.DBRepository) error {
+		client := pg.client(ctx)
+
+		row, err := client.IdempotencyKey.Query().
+			Where(idempotencykey.Key(record.Key)).
+			Only(ctx)
+		switch {
+		case err == nil && row.ExpiresAt.After(time.Now()):
+			held = toIdempotencyRecord(row)
+			return nil
+		case err == nil:
+			// Expired, the key can be claimed again
+			if err := client.IdempotencyKey.DeleteOne(row).Exec(ctx); err != nil {
+				return err
+			}
+		case !ent.IsNotFound(err):
+			return err
+		}
+
+		return client.IdempotencyKey.Create().
+			SetKey(record.Key).
+			SetFingerprint(record.Fingerprint).
+			SetExpiresAt(record.ExpiresAt.UTC()).
+			Exec(ctx)
+	})
+
+	// Claimed by a concurrent request in the meantime
+	if ent.IsConstraintError(err) {
+		var row *ent.IdempotencyKey
+		row, err = pg.client(ctx).IdempotencyKey.Query().
+			Where(idempotencykey.Key(record.Key)).
+			Only(ctx)
+		if err == nil {
+			held = toIdempotencyRecord(row)
+		}
+	}
+
+	if err != nil {
+		log.Err(err).
+			Msg("error while claiming idempotency key")
+
+		return nil, translateError(err)
+	}
+
+	return held, nil
+}
+
+func (pg *PostgresqlClient) IdempotencyKeyComplete(ctx context.Context, record models.IdempotencyRecord) error {
+	log := logger.
+		FromContext(ctx).
+		With().
+		Str("method", "postgresql.IdempotencyKeyComplete").
+		Logger()
+
+	updated, err := pg.client(ctx).IdempotencyKey.Update().
+		Where(idempotencykey.Key(record.Key)).
+		SetStatus(record.Status).
+		SetHeader(record.Header).
+		SetBody(record.Body).
+		Save(ctx)
+	if err == nil && updated == 0 {
+		return database.ErrNotFound
+	}
+
+	if err != nil {
+		log.Err(err).
+			Msg("error while storing idempotent response")
+
+		return translateError(err)
+	}
+
+	return nil
+}
+
+func (pg *PostgresqlClient) IdempotencyKeyRelease(ctx context.Context, key string) error {
+	log := logger.
+		FromContext(ctx).
+		With().
+		Str("method", "postgresql.IdempotencyKeyRelease").
+		Logger()
+
+	_, err := pg.client(ctx).IdempotencyKey.Delete().
+		Where(idempotencykey.Key(key)).
+		Exec(ctx)
+
+	if err != nil {
+		log.Err(err).
+			Msg("error while releasing idempotency key")
+
+		return translateError(err)
+	}
+
+	return nil
+}
+
+// Permanently removes the idempotency keys expired before `before`. Returns
+// how many were removed.
+func (pg *PostgresqlClient) PurgeIdempotencyKeys(ctx context.Context, before time.Time, l *zerolog.Logger) (int, error) {
+	logger := l.With().
+		Str("method", "postgresql.PurgeIdempotencyKeys").
+		Time("before", before).
+		Logger()
+
+	keys, err := pg.client(ctx).IdempotencyKey.Delete().
+		Where(idempotencykey.ExpiresAtLT(before.UTC())).
+		Exec(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("error purging expired idempotency keys")
+		return 0, translateError(err)
+	}
+
+	logger.Info().
+		Int("keys", keys).
+		Msg("purged expired idempotency keys")
+
+	return keys, nil
+}
+
+func toIdempotencyRecord(row *ent.IdempotencyKey) *models.IdempotencyRecord {
+	return &models.IdempotencyRecord{
+		Key:         row.Key,
+		Fingerprint: row.Fingerprint,
+		Status:      row.Status,
+		Header:      row.Header,
+		Body:        row.Body,
+		ExpiresAt:   row.ExpiresAt.UTC(),
+	}
+}
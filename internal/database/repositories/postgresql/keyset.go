@@ -0,0 +1,49 @@
+package postgresql
+
+import (
+	"entgo.io/ent/dialect/sql"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
+)
+
+// Orders the query by the sort fields, in order
+func orderBy(sort []models.SortField) func(*sql.Selector) {
+	return func(s *sql.Selector) {
+		for _, sf := range sort {
+			if sf.Desc {
+				ent.Desc(sf.Field)(s)
+			} else {
+				ent.Asc(sf.Field)(s)
+			}
+		}
+	}
+}
+
+// Keeps the rows placed after the cursor for the given ordering, i.e.
+//
+//	(a > x) OR (a = x AND b > y) OR (a = x AND b = y AND id > z)
+//
+// `values` holds the cursor value of every sort field.
+func afterCursor(sort []models.SortField, values map[string]any) func(*sql.Selector) {
+	return func(s *sql.Selector) {
+		ors := make([]*sql.Predicate, 0, len(sort))
+
+		for i, sf := range sort {
+			ands := make([]*sql.Predicate, 0, i+1)
+			for _, prev := range sort[:i] {
+				ands = append(ands, sql.EQ(s.C(prev.Field), values[prev.Field]))
+			}
+
+			if sf.Desc {
+				ands = append(ands, sql.LT(s.C(sf.Field), values[sf.Field]))
+			} else {
+				ands = append(ands, sql.GT(s.C(sf.Field), values[sf.Field]))
+			}
+
+			ors = append(ors, sql.And(ands...))
+		}
+
+		s.Where(sql.Or(ors...))
+	}
+}
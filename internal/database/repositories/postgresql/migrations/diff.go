@@ -0,0 +1,110 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"ariga.io/atlas/sql/migrate"
+	"ariga.io/atlas/sql/sqltool"
+	entsql "entgo.io/ent/dialect/sql"
+	"entgo.io/ent/dialect/sql/schema"
+
+	entmigrate "github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent/migrate"
+)
+
+// File is a file of a migration directory.
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// Computes the migration named `name` that brings the migrations in `dir` up
+// to date with the ent schema. The migrations are replayed on `dev`, an
+// empty database of the ent dialect `dialectName`, to know the schema they
+// build.
+//
+// Returns the files to write into `dir`, the migration and the updated
+// `atlas.sum`, or none when there are no changes.
+func Diff(ctx context.Context, dev *sql.DB, dialectName string, dir fs.FS, name string) ([]File, error) {
+	mem := &migrate.MemDir{}
+	existing := map[string][]byte{}
+	err := fs.WalkDir(dir, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		content, err := fs.ReadFile(dir, p)
+		if err != nil {
+			return err
+		}
+		existing[p] = content
+
+		return mem.WriteFile(p, content)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := schema.NewMigrate(
+		entsql.OpenDB(dialectName, dev),
+		schema.WithDir(upDir{mem}),
+		schema.WithMigrationMode(schema.ModeReplay),
+		schema.WithFormatter(sqltool.GolangMigrateFormatter),
+		schema.WithDropColumn(true),
+		schema.WithDropIndex(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.NamedDiff(ctx, name, entmigrate.Tables...); err != nil {
+		return nil, fmt.Errorf("computing the migration: %w", err)
+	}
+
+	written, err := mem.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []File
+	for _, f := range written {
+		if old, ok := existing[f.Name()]; !ok || !bytes.Equal(old, f.Bytes()) {
+			result = append(result, File{Name: f.Name(), Content: f.Bytes()})
+		}
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	sum, err := fs.ReadFile(mem, migrate.HashFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(result, File{Name: migrate.HashFileName, Content: sum}), nil
+}
+
+// Directory replaying only the `.up.sql` files, the `.down.sql` ones revert
+// them
+type upDir struct {
+	*migrate.MemDir
+}
+
+func (d upDir) Files() ([]migrate.File, error) {
+	files, err := d.MemDir.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]migrate.File, 0, len(files))
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), ".up.sql") {
+			result = append(result, f)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,412 @@
+// Package migrations keeps the versioned SQL migrations of the ent schema,
+// one directory per dialect, and applies them.
+//
+// Files follow the golang-migrate layout, `<version>_<name>.up.sql` and its
+// `.down.sql` counterpart, and are generated with `Diff`. The applied
+// versions are tracked in the `schema_revisions` table.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"ariga.io/atlas/sql/migrate"
+	"ariga.io/atlas/sql/postgres"
+	"ariga.io/atlas/sql/schema"
+	"ariga.io/atlas/sql/sqlite"
+	"entgo.io/ent/dialect"
+)
+
+//go:embed postgres sqlite
+var files embed.FS
+
+// Directory of the migrations of the ent dialect `dialectName`
+func Dir(dialectName string) (fs.FS, error) {
+	switch dialectName {
+	case dialect.Postgres:
+		return fs.Sub(files, "postgres")
+	case dialect.SQLite:
+		return fs.Sub(files, "sqlite")
+	default:
+		return nil, fmt.Errorf("no migrations for dialect %q", dialectName)
+	}
+}
+
+// Migration is a versioned change of the schema, with the statements to
+// apply and revert it.
+type Migration struct {
+	Version string
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Revision is the state of a migration in a database, `AppliedAt` is nil
+// when it is pending.
+type Revision struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies the migrations of its dialect to a database.
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []Migration
+}
+
+func New(db *sql.DB, dialectName string) (*Migrator, error) {
+	dir, err := Dir(dialectName)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		dialect:    dialectName,
+		migrations: migrations,
+	}, nil
+}
+
+// Every migration, oldest first, along with when it was applied
+func (m *Migrator) Status(ctx context.Context) ([]Revision, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Revision, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		rev := Revision{Migration: mig}
+		if at, ok := applied[mig.Version]; ok {
+			rev.AppliedAt = &at
+		}
+		result = append(result, rev)
+	}
+
+	return result, nil
+}
+
+// Migrations not applied yet, oldest first
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Migration
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			result = append(result, mig)
+		}
+	}
+
+	return result, nil
+}
+
+// Applies every pending migration, each in its own transaction, and returns
+// the applied ones
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, mig := range pending {
+		err := m.inTx(ctx, mig.Up, fmt.Sprintf(
+			"INSERT INTO schema_revisions (version, name, applied_at) VALUES (%s, %s, %s)",
+			m.placeholder(1), m.placeholder(2), m.placeholder(3),
+		), mig.Version, mig.Name, time.Now().UTC())
+		if err != nil {
+			return pending[:i], fmt.Errorf("applying migration %s_%s: %w", mig.Version, mig.Name, err)
+		}
+	}
+
+	return pending, nil
+}
+
+// Reverts the last `n` applied migrations, newest first, and returns the
+// reverted ones
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+	if n < len(versions) {
+		versions = versions[:n]
+	}
+
+	reverted := make([]Migration, 0, len(versions))
+	for _, version := range versions {
+		mig, ok := m.find(version)
+		if !ok {
+			return reverted, fmt.Errorf("no migration file for applied version %s", version)
+		}
+
+		err := m.inTx(ctx, mig.Down, fmt.Sprintf(
+			"DELETE FROM schema_revisions WHERE version = %s", m.placeholder(1),
+		), mig.Version)
+		if err != nil {
+			return reverted, fmt.Errorf("reverting migration %s_%s: %w", mig.Version, mig.Name, err)
+		}
+		reverted = append(reverted, mig)
+	}
+
+	return reverted, nil
+}
+
+// Marks the migrations up to `version` as applied without running them, for
+// databases whose schema was created before migrations were versioned.
+//
+// The migrations are replayed on `dev`, an empty database of the same
+// dialect, and nothing is marked unless the schema of the database matches
+// the one they build.
+func (m *Migrator) Baseline(ctx context.Context, version string, dev *sql.DB) ([]Migration, error) {
+	if _, ok := m.find(version); !ok {
+		return nil, fmt.Errorf("no migration with version %s", version)
+	}
+
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkSchema(ctx, version, dev); err != nil {
+		return nil, err
+	}
+
+	var marked []Migration
+	for _, mig := range pending {
+		if mig.Version > version {
+			break
+		}
+
+		err := m.inTx(ctx, "", fmt.Sprintf(
+			"INSERT INTO schema_revisions (version, name, applied_at) VALUES (%s, %s, %s)",
+			m.placeholder(1), m.placeholder(2), m.placeholder(3),
+		), mig.Version, mig.Name, time.Now().UTC())
+		if err != nil {
+			return marked, err
+		}
+		marked = append(marked, mig)
+	}
+
+	return marked, nil
+}
+
+// HELPERS
+// Fails unless the schema of the database is the one the migrations up to
+// `version` build on `dev`
+func (m *Migrator) checkSchema(ctx context.Context, version string, dev *sql.DB) error {
+	for _, mig := range m.migrations {
+		if mig.Version > version {
+			break
+		}
+
+		stmts, err := migrate.NewLocalFile(mig.Version+".up.sql", []byte(mig.Up)).Stmts()
+		if err != nil {
+			return err
+		}
+		for _, stmt := range stmts {
+			if _, err := dev.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("replaying migration %s_%s on the dev database: %w", mig.Version, mig.Name, err)
+			}
+		}
+	}
+
+	live, err := m.inspect(ctx, m.db)
+	if err != nil {
+		return fmt.Errorf("inspecting the database: %w", err)
+	}
+	expected, err := m.inspect(ctx, dev)
+	if err != nil {
+		return fmt.Errorf("inspecting the dev database: %w", err)
+	}
+	// Only the tables are compared, whatever schema they live in
+	live.Name = expected.Name
+
+	drv, err := m.driver(dev)
+	if err != nil {
+		return err
+	}
+	changes, err := drv.SchemaDiff(live, expected)
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		return fmt.Errorf("schema of the database doesn't match migration %s: %s", version, describe(changes))
+	}
+
+	return nil
+}
+
+// Tables of the schema `db` is connected to, besides the revisions table
+func (m *Migrator) inspect(ctx context.Context, db *sql.DB) (*schema.Schema, error) {
+	drv, err := m.driver(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return drv.InspectSchema(ctx, "", &schema.InspectOptions{
+		Exclude: []string{"schema_revisions"},
+	})
+}
+
+func (m *Migrator) driver(db *sql.DB) (migrate.Driver, error) {
+	if m.dialect == dialect.Postgres {
+		return postgres.Open(db)
+	}
+
+	return sqlite.Open(db)
+}
+
+// Tables that are missing, unexpected or different, as in `changes` that
+// would turn the database into the expected schema
+func describe(changes []schema.Change) string {
+	result := make([]string, 0, len(changes))
+	for _, c := range changes {
+		switch c := c.(type) {
+		case *schema.AddTable:
+			result = append(result, "missing table "+c.T.Name)
+		case *schema.DropTable:
+			result = append(result, "unexpected table "+c.T.Name)
+		case *schema.ModifyTable:
+			result = append(result, "table "+c.T.Name+" differs")
+		default:
+			result = append(result, fmt.Sprintf("%T", c))
+		}
+	}
+
+	return strings.Join(result, ", ")
+}
+
+// When each version was applied, creating the revisions table if needed
+func (m *Migrator) applied(ctx context.Context) (map[string]time.Time, error) {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_revisions (
+	version varchar(14) NOT NULL PRIMARY KEY,
+	name varchar(255) NOT NULL,
+	applied_at timestamp NOT NULL
+)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating the revisions table: %w", err)
+	}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT version, applied_at FROM schema_revisions")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := map[string]time.Time{}
+	for rows.Next() {
+		var version string
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		result[version] = appliedAt.UTC()
+	}
+
+	return result, rows.Err()
+}
+
+// Runs the statements of `script` followed by `revision`, which records the
+// change, as a single transaction
+func (m *Migrator) inTx(ctx context.Context, script, revision string, args ...any) (err error) {
+	stmts, err := migrate.NewLocalFile("migration.sql", []byte(script)).Stmts()
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, revision, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) find(version string) (Migration, bool) {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig, true
+		}
+	}
+
+	return Migration{}, false
+}
+
+func (m *Migrator) placeholder(n int) string {
+	if m.dialect == dialect.Postgres {
+		return fmt.Sprintf("$%d", n)
+	}
+
+	return "?"
+}
+
+// Migrations of `dir`, oldest first
+func load(dir fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(dir, "*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	result := make([]Migration, 0, len(names))
+	for _, name := range names {
+		base := strings.TrimSuffix(name, ".up.sql")
+		version, desc, _ := strings.Cut(base, "_")
+		if err := migrate.CheckVersion(version); err != nil {
+			return nil, fmt.Errorf("migration %s: %w", name, err)
+		}
+
+		up, err := fs.ReadFile(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		down, err := fs.ReadFile(dir, path.Join(path.Dir(name), base+".down.sql"))
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has no down file: %w", name, err)
+		}
+
+		result = append(result, Migration{
+			Version: version,
+			Name:    desc,
+			Up:      string(up),
+			Down:    string(down),
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,171 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"testing/fstest"
+
+	"entgo.io/ent/dialect"
+	entsql "entgo.io/ent/dialect/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:?_pragma=foreign_keys(1)")
+	require.NoError(t, err)
+	// Every connection would get its own in-memory database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	return db
+}
+
+func newTestMigrator(t *testing.T) (*Migrator, *sql.DB) {
+	t.Helper()
+
+	db := newTestDB(t)
+	m, err := New(db, dialect.SQLite)
+	require.NoError(t, err)
+
+	return m, db
+}
+
+func versions(migrations []Migration) []string {
+	result := make([]string, 0, len(migrations))
+	for _, m := range migrations {
+		result = append(result, m.Version)
+	}
+
+	return result
+}
+
+func Test_Migrator(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should apply every pending migration once", func(t *testing.T) {
+		m, _ := newTestMigrator(t)
+
+		applied, err := m.Up(ctx)
+		require.NoError(t, err)
+		again, err := m.Up(ctx)
+		require.NoError(t, err)
+		pending, err := m.Pending(ctx)
+		require.NoError(t, err)
+		status, err := m.Status(ctx)
+		require.NoError(t, err)
+
+		assert.Equal(t, versions(m.migrations), versions(applied))
+		assert.Empty(t, again)
+		assert.Empty(t, pending)
+		require.Len(t, status, len(m.migrations))
+		for _, rev := range status {
+			assert.NotNil(t, rev.AppliedAt, rev.Version)
+		}
+	})
+
+	t.Run("should report every migration as pending on an empty database", func(t *testing.T) {
+		m, _ := newTestMigrator(t)
+
+		pending, err := m.Pending(ctx)
+		require.NoError(t, err)
+		status, err := m.Status(ctx)
+		require.NoError(t, err)
+
+		assert.Equal(t, versions(m.migrations), versions(pending))
+		for _, rev := range status {
+			assert.Nil(t, rev.AppliedAt, rev.Version)
+		}
+	})
+
+	t.Run("should revert the last migrations, even with data in the tables", func(t *testing.T) {
+		m, db := newTestMigrator(t)
+		_, err := m.Up(ctx)
+		require.NoError(t, err)
+		client := ent.NewClient(ent.Driver(entsql.OpenDB(dialect.SQLite, db)))
+		u, err := client.User.Create().SetName("John Doe").SetEmail("johnnydoe@gmail.com").Save(ctx)
+		require.NoError(t, err)
+		_, err = client.Post.Create().SetTitle("coolio").SetContent("coolest content").SetUserID(u.ID).Save(ctx)
+		require.NoError(t, err)
+
+		reverted, err := m.Down(ctx, 1)
+		require.NoError(t, err)
+		pending, err := m.Pending(ctx)
+		require.NoError(t, err)
+
+		last := m.migrations[len(m.migrations)-1]
+		assert.Equal(t, []string{last.Version}, versions(reverted))
+		assert.Equal(t, []string{last.Version}, versions(pending))
+	})
+
+	t.Run("should mark migrations as applied without running them", func(t *testing.T) {
+		m, db := newTestMigrator(t)
+		// Schema created before migrations were versioned
+		client := ent.NewClient(ent.Driver(entsql.OpenDB(dialect.SQLite, db)))
+		require.NoError(t, client.Schema.Create(ctx))
+		last := m.migrations[len(m.migrations)-1].Version
+
+		marked, err := m.Baseline(ctx, last, newTestDB(t))
+		require.NoError(t, err)
+		_, unknownErr := m.Baseline(ctx, "20000101000000", newTestDB(t))
+
+		assert.Equal(t, versions(m.migrations), versions(marked))
+		assert.Error(t, unknownErr)
+	})
+
+	t.Run("should not mark migrations the schema doesn't match", func(t *testing.T) {
+		m, db := newTestMigrator(t)
+		client := ent.NewClient(ent.Driver(entsql.OpenDB(dialect.SQLite, db)))
+		require.NoError(t, client.Schema.Create(ctx))
+		first := m.migrations[0].Version
+
+		// Has the tables of the later migrations too
+		_, tooNewErr := m.Baseline(ctx, first, newTestDB(t))
+		// Has no tables at all
+		empty, _ := newTestMigrator(t)
+		_, emptyErr := empty.Baseline(ctx, first, newTestDB(t))
+		pending, err := m.Pending(ctx)
+		require.NoError(t, err)
+
+		assert.ErrorContains(t, tooNewErr, "unexpected table idempotency_keys")
+		assert.ErrorContains(t, emptyErr, "missing table users")
+		assert.Equal(t, versions(m.migrations), versions(pending))
+	})
+}
+
+func Test_Diff(t *testing.T) {
+	t.Run("should find the migrations in sync with the ent schema", func(t *testing.T) {
+		dir, err := Dir(dialect.SQLite)
+		require.NoError(t, err)
+
+		files, err := Diff(context.Background(), newTestDB(t), dialect.SQLite, dir, "changes")
+
+		require.NoError(t, err)
+		assert.Empty(t, files)
+	})
+
+	t.Run("should write the whole schema for an empty directory", func(t *testing.T) {
+		files, err := Diff(context.Background(), newTestDB(t), dialect.SQLite, fstest.MapFS{}, "init")
+
+		require.NoError(t, err)
+		require.Len(t, files, 3)
+		assert.Regexp(t, `^\d{14}_init\.down\.sql$`, files[0].Name)
+		assert.Regexp(t, `^\d{14}_init\.up\.sql$`, files[1].Name)
+		assert.Contains(t, string(files[1].Content), "CREATE TABLE `users`")
+		assert.Equal(t, "atlas.sum", files[2].Name)
+	})
+
+	t.Run("should not have migrations for unknown dialects", func(t *testing.T) {
+		_, err := Dir(dialect.MySQL)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,6 @@
+-- reverse: create "posts" table
+DROP TABLE "posts";
+-- reverse: create index "users_email_key" to table: "users"
+DROP INDEX "users_email_key";
+-- reverse: create "users" table
+DROP TABLE "users";
@@ -0,0 +1,6 @@
+-- create "idempotency_keys" table
+CREATE TABLE "idempotency_keys" ("id" bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY, "key" character varying NOT NULL, "fingerprint" character varying NOT NULL, "status" bigint NOT NULL DEFAULT 0, "header" jsonb NULL, "body" bytea NULL, "created_at" timestamptz NOT NULL, "expires_at" timestamptz NOT NULL, PRIMARY KEY ("id"));
+-- create index "idempotency_keys_key_key" to table: "idempotency_keys"
+CREATE UNIQUE INDEX "idempotency_keys_key_key" ON "idempotency_keys" ("key");
+-- create index "idempotencykey_expires_at" to table: "idempotency_keys"
+CREATE INDEX "idempotencykey_expires_at" ON "idempotency_keys" ("expires_at");
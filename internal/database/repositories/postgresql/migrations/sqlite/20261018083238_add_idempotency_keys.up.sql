@@ -0,0 +1,6 @@
+-- create "idempotency_keys" table
+CREATE TABLE `idempotency_keys` (`id` integer NOT NULL PRIMARY KEY AUTOINCREMENT, `key` text NOT NULL, `fingerprint` text NOT NULL, `status` integer NOT NULL DEFAULT (0), `header` json NULL, `body` blob NULL, `created_at` datetime NOT NULL, `expires_at` datetime NOT NULL);
+-- create index "idempotency_keys_key_key" to table: "idempotency_keys"
+CREATE UNIQUE INDEX `idempotency_keys_key_key` ON `idempotency_keys` (`key`);
+-- create index "idempotencykey_expires_at" to table: "idempotency_keys"
+CREATE INDEX `idempotencykey_expires_at` ON `idempotency_keys` (`expires_at`);
@@ -3,14 +3,21 @@ package postgresql
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 
 	"entgo.io/ent/dialect"
 	entsql "entgo.io/ent/dialect/sql"
+	"entgo.io/ent/dialect/sql/sqlgraph"
 	"github.com/rs/zerolog"
 
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent"
-	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent/migrate"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent/post"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent/predicate"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent/user"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/migrations"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/logger"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
 )
@@ -18,6 +25,10 @@ import (
 type PostgresqlClient struct {
 	*ent.Client
 	connection *sql.DB
+	// ent dialect of the database
+	dialect string
+	// Set when every call runs in a transaction, see `WithTx`
+	tx *ent.Tx
 }
 
 func (pg *PostgresqlClient) Connection() *sql.DB {
@@ -34,9 +45,9 @@ func (pg *PostgresqlClient) Ping(ctx context.Context) error {
 	log.Info().
 		Msg("Pinging DB")
 
-	if err := pg.Connection().Ping(); err != nil {
+	if err := pg.Connection().PingContext(ctx); err != nil {
 		log.Error().AnErr("error", err).Msg("Failed to ping DB")
-		return err
+		return translateError(err)
 	}
 
 	log.Info().Msg("successfully pinged database")
@@ -56,10 +67,14 @@ func (pg *PostgresqlClient) UserCreate(ctx context.Context, user models.User) (*
 		Interface("user", user).
 		Msg("creating user")
 
-	u, err := pg.User.
+	// Not modified since created, the defaults would be a few µs apart
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	u, err := pg.client(ctx).User.
 		Create().
 		SetName(user.Name).
 		SetEmail(user.Email).
+		SetCreatedAt(now).
+		SetUpdatedAt(now).
 		Save(ctx)
 
 	if err != nil {
@@ -69,32 +84,136 @@ func (pg *PostgresqlClient) UserCreate(ctx context.Context, user models.User) (*
 				Msg("error while creating user")
 		}
 
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	log.Info().
 		Interface("user", u).
 		Msg("user created")
 
-	return &models.User{
-		ID:    u.ID,
-		Email: u.Email,
-		Name:  u.Name,
-	}, err
+	return toUser(u), err
 }
 
-func (pg *PostgresqlClient) UserGetAll(ctx context.Context) ([]*models.User, error) {
+func (pg *PostgresqlClient) UserCreateBulk(ctx context.Context, users []models.User, opts models.BulkOptions) ([]*models.User, error) {
+	log := logger.
+		FromContext(ctx).
+		With().
+		Str("method", "postgresql.UserCreateBulk").
+		Logger()
+
+	log.Info().
+		Int("users", len(users)).
+		Bool("partial", opts.Partial).
+		Msg("creating users")
+
+	created := make([]*models.User, len(users))
+	failed := map[int]error{}
+	err := pg.WithTx(ctx, func(ctx context.Context, _ database.DBRepository) error {
+		client := pg.client(ctx)
+
+		// A single conflict would fail the whole insert, the taken emails are
+		// told apart beforehand. Deleted users keep theirs until purged.
+		emails := make([]string, 0, len(users))
+		for _, u := range users {
+			emails = append(emails, u.Email)
+		}
+		taken, err := client.User.Query().
+			Where(user.EmailIn(emails...)).
+			Select(user.FieldEmail).
+			Strings(withDeleted(ctx))
+		if err != nil {
+			return err
+		}
+
+		seen := map[string]bool{}
+		for _, email := range taken {
+			seen[email] = true
+		}
+		for i, u := range users {
+			if seen[u.Email] {
+				failed[i] = &database.ConflictError{Field: "email"}
+			}
+			seen[u.Email] = true
+		}
+		if len(failed) > 0 && !opts.Partial {
+			return nil
+		}
+
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		builders := make([]*ent.UserCreate, 0, len(users))
+		indexes := make([]int, 0, len(users))
+		for i, u := range users {
+			if failed[i] != nil {
+				continue
+			}
+			builders = append(builders, client.User.Create().
+				SetName(u.Name).
+				SetEmail(u.Email).
+				SetCreatedAt(now).
+				SetUpdatedAt(now))
+			indexes = append(indexes, i)
+		}
+		if len(builders) == 0 {
+			return nil
+		}
+
+		rows, err := client.User.CreateBulk(builders...).Save(ctx)
+		if err != nil {
+			return err
+		}
+		for j, row := range rows {
+			created[indexes[j]] = toUser(row)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		if !ent.IsConstraintError(err) {
+			log.Err(err).
+				Msg("error while creating users")
+		}
+
+		return nil, translateError(err)
+	}
+
+	log.Info().
+		Int("created", len(users)-len(failed)).
+		Int("failed", len(failed)).
+		Msg("users created")
+
+	if len(failed) > 0 {
+		return created, &database.BulkError{Errors: failed}
+	}
+
+	return created, nil
+}
+
+func (pg *PostgresqlClient) UserGetAll(ctx context.Context, query models.UserQuery) (*models.Page[*models.User], error) {
 	log := logger.
 		FromContext(ctx).
 		With().
 		Str("method", "postgresql.UserGetAll").
 		Logger()
 
-	users, err := pg.User.Query().All(ctx)
+	q := pg.client(ctx).User.
+		Query().
+		Order(user.ByID()).
+		Limit(query.Limit + 1)
+
+	if query.After != nil {
+		q = q.Where(user.IDGT(query.After.ID))
+	}
+	if query.Include.Posts {
+		q = q.WithPosts(orderPostsByID)
+	}
+
+	users, err := q.All(ctx)
 
 	if err != nil {
 		log.Err(err).
 			Msg("error while querying users")
+		return nil, translateError(err)
 	}
 
 	log.Info().
@@ -103,24 +222,30 @@ func (pg *PostgresqlClient) UserGetAll(ctx context.Context) ([]*models.User, err
 
 	result := make([]*models.User, 0, len(users))
 	for _, u := range users {
-		result = append(result, &models.User{
-			ID:    u.ID,
-			Name:  u.Name,
-			Email: u.Email,
-		})
+		result = append(result, toUser(u))
 	}
 
-	return result, err
+	return models.NewPage(result, query.Limit, func(u *models.User) *models.Cursor {
+		return &models.Cursor{ID: u.ID}
+	}), nil
 }
 
-func (pg *PostgresqlClient) UserGetByID(ctx context.Context, id uint64) (*models.User, error) {
+func (pg *PostgresqlClient) UserGetByID(ctx context.Context, id uint64, include models.UserInclude) (*models.User, error) {
 	log := logger.
 		FromContext(ctx).
 		With().
 		Str("method", "postgresql.UserGet").
 		Logger()
 
-	user, err := pg.User.Get(ctx, id)
+	query := pg.client(ctx).User.
+		Query().
+		Where(user.ID(id))
+
+	if include.Posts {
+		query = query.WithPosts(orderPostsByID)
+	}
+
+	u, err := query.Only(ctx)
 
 	if err != nil {
 		if !ent.IsNotFound(err) {
@@ -128,71 +253,252 @@ func (pg *PostgresqlClient) UserGetByID(ctx context.Context, id uint64) (*models
 				Msg("error while querying user")
 		}
 
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	log.Info().
-		Interface("user", user).
+		Interface("user", u).
 		Msg("user retrieved from DB")
 
-	return &models.User{
-		ID:    id,
-		Email: user.Email,
-		Name:  user.Name,
-	}, err
+	return toUser(u), err
 }
 
-func (pg *PostgresqlClient) UserDeleteByID(ctx context.Context, id uint64) error {
+func (pg *PostgresqlClient) UserGetPosts(ctx context.Context, id uint64, query models.PostQuery) (*models.Page[*models.Post], error) {
 	log := logger.
 		FromContext(ctx).
 		With().
-		Str("method", "postgresql.UserDeleteByID").
+		Str("method", "postgresql.UserGetPosts").
 		Logger()
 
-	err := pg.User.DeleteOneID(id).Exec(ctx)
+	u, err := pg.client(ctx).User.Get(ctx, id)
 
 	if err != nil {
 		if !ent.IsNotFound(err) {
 			log.Err(err).
-				Msg("error while deleting user")
+				Msg("error while querying user")
 		}
 
+		return nil, translateError(err)
+	}
+
+	page, err := postPage(ctx, u.QueryPosts(), query)
+
+	if err != nil {
+		log.Err(err).
+			Msg("error while querying user posts")
+		return nil, translateError(err)
+	}
+
+	log.Info().
+		Uint64("id", id).
+		Interface("posts", page.Items).
+		Msg("user posts retrieved from DB")
+
+	return page, nil
+}
+
+func (pg *PostgresqlClient) UserDeleteByID(ctx context.Context, id uint64, opts models.DeleteOptions) error {
+	log := logger.
+		FromContext(ctx).
+		With().
+		Str("method", "postgresql.UserDeleteByID").
+		Logger()
+
+	var posts int
+	err := pg.WithTx(ctx, func(ctx context.Context, _ database.DBRepository) (err error) {
+		posts, err = userSoftDelete(ctx, pg.client(ctx), id, opts)
 		return err
+	})
+
+	if err != nil {
+		if opts.IfUpdatedAt != nil {
+			err = pg.userConditionalWriteError(ctx, id, err)
+		}
+		var hasPosts *database.UserHasPostsError
+		if !ent.IsNotFound(err) && !errors.Is(err, database.ErrPreconditionFailed) && !errors.As(err, &hasPosts) {
+			log.Err(err).
+				Msg("error while deleting user")
+		}
+
+		return translateError(err)
 	}
 
 	log.Info().
 		Uint64("id", id).
+		Int("posts", posts).
 		Msg("user deleted")
 
 	return nil
 }
 
-func (pg *PostgresqlClient) UserUpdate(ctx context.Context, user models.UserUpdate) (*models.User, error) {
+// Soft deletes the user, see `Purge`, and with `opts.Cascade` its posts at
+// the same instant, so `UserRestoreByID` can tell which posts to bring back.
+// Returns how many posts were deleted.
+func userSoftDelete(ctx context.Context, client *ent.Client, id uint64, opts models.DeleteOptions) (int, error) {
+	// Restoring matches the posts by this exact value, it must compare equal
+	// once stored
+	deletedAt := time.Now().UTC().Truncate(time.Microsecond)
+
+	del := client.User.UpdateOneID(id).
+		Where(user.DeletedAtIsNil()).
+		SetDeletedAt(deletedAt)
+	if opts.IfUpdatedAt != nil {
+		del = del.Where(user.UpdatedAt(opts.IfUpdatedAt.UTC()))
+	}
+
+	if err := del.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	posts, err := client.Post.Query().Where(post.UserID(id)).Count(ctx)
+	if err != nil || posts == 0 {
+		return 0, err
+	}
+	if !opts.Cascade {
+		return 0, &database.UserHasPostsError{Posts: posts}
+	}
+
+	return client.Post.Update().
+		Where(post.UserID(id), post.DeletedAtIsNil()).
+		SetDeletedAt(deletedAt).
+		Save(ctx)
+}
+
+func (pg *PostgresqlClient) UserUpdate(ctx context.Context, update models.UserUpdate) (*models.User, error) {
 	log := logger.
 		FromContext(ctx).
 		With().
 		Str("method", "postgresql.UserUpdate").
 		Logger()
 
-	u, err := pg.User.UpdateOneID(*user.ID).
-		SetName(user.Name).
-		SetEmail(user.Email).
-		Save(ctx)
+	upd := pg.client(ctx).User.UpdateOneID(*update.ID).
+		Where(user.DeletedAtIsNil()).
+		SetName(update.Name).
+		SetEmail(update.Email)
+	if update.IfUpdatedAt != nil {
+		upd = upd.Where(user.UpdatedAt(update.IfUpdatedAt.UTC()))
+	}
+
+	u, err := upd.Save(ctx)
 
 	if err != nil {
-		if !ent.IsNotFound(err) && !ent.IsConstraintError(err) {
+		if update.IfUpdatedAt != nil {
+			err = pg.userConditionalWriteError(ctx, *update.ID, err)
+		}
+		if !ent.IsNotFound(err) && !ent.IsConstraintError(err) && !errors.Is(err, database.ErrPreconditionFailed) {
 			log.Err(err).
 				Msg("error while updating user")
 		}
 
+		return nil, translateError(err)
+	}
+
+	return toUser(u), err
+}
+
+func (pg *PostgresqlClient) UserPatch(ctx context.Context, patch models.UserPatch) (*models.User, error) {
+	log := logger.
+		FromContext(ctx).
+		With().
+		Str("method", "postgresql.UserPatch").
+		Logger()
+
+	upd := pg.client(ctx).User.UpdateOneID(*patch.ID).
+		Where(user.DeletedAtIsNil()).
+		SetNillableName(patch.Name).
+		SetNillableEmail(patch.Email)
+	if patch.IfUpdatedAt != nil {
+		upd = upd.Where(user.UpdatedAt(patch.IfUpdatedAt.UTC()))
+	}
+
+	u, err := upd.Save(ctx)
+
+	if err != nil {
+		if patch.IfUpdatedAt != nil {
+			err = pg.userConditionalWriteError(ctx, *patch.ID, err)
+		}
+		if !ent.IsNotFound(err) && !ent.IsConstraintError(err) && !errors.Is(err, database.ErrPreconditionFailed) {
+			log.Err(err).
+				Msg("error while patching user")
+		}
+
+		return nil, translateError(err)
+	}
+
+	log.Info().
+		Interface("user", u).
+		Msg("user patched")
+
+	return toUser(u), err
+}
+
+// Also restores the posts deleted along with the user, see `userSoftDelete`
+func (pg *PostgresqlClient) UserRestoreByID(ctx context.Context, id uint64) (*models.User, error) {
+	log := logger.
+		FromContext(ctx).
+		With().
+		Str("method", "postgresql.UserRestoreByID").
+		Logger()
+
+	var u *ent.User
+	err := pg.WithTx(ctx, func(ctx context.Context, _ database.DBRepository) (err error) {
+		u, err = userRestore(ctx, pg.client(ctx), id)
+		return err
+	})
+
+	if err != nil {
+		if !ent.IsNotFound(err) {
+			log.Err(err).
+				Msg("error while restoring user")
+		}
+
+		return nil, translateError(err)
+	}
+
+	log.Info().
+		Uint64("id", id).
+		Msg("user restored")
+
+	return toUser(u), nil
+}
+
+func userRestore(ctx context.Context, client *ent.Client, id uint64) (*ent.User, error) {
+	u, err := client.User.Get(withDeleted(ctx), id)
+	// Restoring a user that is not deleted is a no-op
+	if err != nil || u.DeletedAt == nil {
+		return u, err
+	}
+
+	err = client.Post.Update().
+		Where(post.UserID(id), post.DeletedAt(*u.DeletedAt)).
+		ClearDeletedAt().
+		Exec(ctx)
+	if err != nil {
 		return nil, err
 	}
 
-	return &models.User{
-		ID:    u.ID,
-		Email: u.Email,
-		Name:  u.Name,
-	}, err
+	return client.User.UpdateOneID(id).
+		Where(user.DeletedAt(*u.DeletedAt)).
+		ClearDeletedAt().
+		Save(ctx)
+}
+
+// A conditional write that matched no row either targeted a missing user or
+// a user modified since, only the latter is a failed precondition
+func (pg *PostgresqlClient) userConditionalWriteError(ctx context.Context, id uint64, err error) error {
+	if !ent.IsNotFound(err) {
+		return err
+	}
+
+	exists, existsErr := pg.client(ctx).User.Query().Where(user.ID(id)).Exist(ctx)
+	if existsErr != nil {
+		return existsErr
+	}
+	if exists {
+		return database.ErrPreconditionFailed
+	}
+
+	return err
 }
 
 // POST
@@ -207,20 +513,38 @@ func (pg *PostgresqlClient) PostCreate(ctx context.Context, post models.Post) (*
 		Interface("post", post).
 		Msg("creating post")
 
-	p, err := pg.Post.
+	// The foreign key can't tell a soft deleted user apart, a user missing
+	// altogether is left for it to report
+	deleted, err := pg.client(ctx).User.Query().
+		Where(user.ID(post.UserID), user.DeletedAtNotNil()).
+		Exist(withDeleted(ctx))
+	if err != nil {
+		log.Err(err).
+			Msg("error while checking post user")
+
+		return nil, translateError(err)
+	}
+	if deleted {
+		return nil, database.ErrUserDeleted
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	p, err := pg.client(ctx).Post.
 		Create().
 		SetTitle(post.Title).
 		SetContent(post.Content).
 		SetUserID(post.UserID).
+		SetCreatedAt(now).
+		SetUpdatedAt(now).
 		Save(ctx)
 
 	if err != nil {
-		if !ent.IsConstraintError(err) {
+		if !ent.IsConstraintError(err) && !sqlgraph.IsForeignKeyConstraintError(err) {
 			log.Err(err).
 				Msg("error while creating post")
 		}
 
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	log.Info().
@@ -228,53 +552,152 @@ func (pg *PostgresqlClient) PostCreate(ctx context.Context, post models.Post) (*
 		Msg("post created")
 
 	return &models.Post{
-		ID:      p.ID,
-		Title:   p.Title,
-		Content: p.Content,
-		UserID:  p.ID,
+		ID:        p.ID,
+		Title:     p.Title,
+		Content:   p.Content,
+		UserID:    p.UserID,
+		CreatedAt: p.CreatedAt.UTC(),
+		UpdatedAt: p.UpdatedAt.UTC(),
 	}, err
 }
 
-func (pg *PostgresqlClient) PostGetAll(ctx context.Context) ([]*models.Post, error) {
+func (pg *PostgresqlClient) PostCreateBulk(ctx context.Context, posts []models.Post, opts models.BulkOptions) ([]*models.Post, error) {
+	log := logger.
+		FromContext(ctx).
+		With().
+		Str("method", "postgresql.PostCreateBulk").
+		Logger()
+
+	log.Info().
+		Int("posts", len(posts)).
+		Bool("partial", opts.Partial).
+		Msg("creating posts")
+
+	created := make([]*models.Post, len(posts))
+	failed := map[int]error{}
+	err := pg.WithTx(ctx, func(ctx context.Context, _ database.DBRepository) error {
+		client := pg.client(ctx)
+
+		// A single missing user would fail the whole insert, and the foreign
+		// key can't tell a soft deleted one apart, they are checked beforehand
+		userIDs := make([]uint64, 0, len(posts))
+		for _, p := range posts {
+			userIDs = append(userIDs, p.UserID)
+		}
+		authors, err := client.User.Query().
+			Where(user.IDIn(userIDs...)).
+			Select(user.FieldID, user.FieldDeletedAt).
+			All(withDeleted(ctx))
+		if err != nil {
+			return err
+		}
+
+		deleted := map[uint64]bool{}
+		for _, u := range authors {
+			deleted[u.ID] = u.DeletedAt != nil
+		}
+		for i, p := range posts {
+			isDeleted, exists := deleted[p.UserID]
+			switch {
+			case !exists:
+				failed[i] = database.ErrForeignKey
+			case isDeleted:
+				failed[i] = database.ErrUserDeleted
+			}
+		}
+		if len(failed) > 0 && !opts.Partial {
+			return nil
+		}
+
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		builders := make([]*ent.PostCreate, 0, len(posts))
+		indexes := make([]int, 0, len(posts))
+		for i, p := range posts {
+			if failed[i] != nil {
+				continue
+			}
+			builders = append(builders, client.Post.Create().
+				SetTitle(p.Title).
+				SetContent(p.Content).
+				SetUserID(p.UserID).
+				SetCreatedAt(now).
+				SetUpdatedAt(now))
+			indexes = append(indexes, i)
+		}
+		if len(builders) == 0 {
+			return nil
+		}
+
+		rows, err := client.Post.CreateBulk(builders...).Save(ctx)
+		if err != nil {
+			return err
+		}
+		for j, row := range rows {
+			created[indexes[j]] = toPost(row)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		if !ent.IsConstraintError(err) && !sqlgraph.IsForeignKeyConstraintError(err) {
+			log.Err(err).
+				Msg("error while creating posts")
+		}
+
+		return nil, translateError(err)
+	}
+
+	log.Info().
+		Int("created", len(posts)-len(failed)).
+		Int("failed", len(failed)).
+		Msg("posts created")
+
+	if len(failed) > 0 {
+		return created, &database.BulkError{Errors: failed}
+	}
+
+	return created, nil
+}
+
+func (pg *PostgresqlClient) PostGetAll(ctx context.Context, query models.PostQuery) (*models.Page[*models.Post], error) {
 	log := logger.
 		FromContext(ctx).
 		With().
 		Str("method", "postgresql.PostGetAll").
 		Logger()
 
-	posts, err := pg.Post.Query().All(ctx)
+	page, err := postPage(ctx, pg.client(ctx).Post.Query(), query)
 
 	if err != nil {
 		log.Err(err).
 			Msg("error while querying posts")
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	log.Info().
-		Interface("posts", posts).
+		Interface("posts", page.Items).
 		Msg("posts retrieved from DB")
 
-	result := make([]*models.Post, 0, len(posts))
-	for _, p := range posts {
-		result = append(result, &models.Post{
-			ID:      p.ID,
-			Title:   p.Title,
-			Content: p.Content,
-			UserID:  p.UserID,
-		})
-	}
-
-	return result, err
+	return page, nil
 }
 
-func (pg *PostgresqlClient) PostGetByID(ctx context.Context, id uint64) (*models.Post, error) {
+func (pg *PostgresqlClient) PostGetByID(ctx context.Context, id uint64, include models.PostInclude) (*models.Post, error) {
 	log := logger.
 		FromContext(ctx).
 		With().
 		Str("method", "postgresql.PostGetByID").
 		Logger()
 
-	post, err := pg.Post.Get(ctx, id)
+	query := pg.client(ctx).Post.
+		Query().
+		Where(post.ID(id))
+
+	if include.User {
+		query = query.WithUser()
+	}
+
+	p, err := query.Only(ctx)
 
 	if err != nil {
 		if !ent.IsNotFound(err) {
@@ -282,37 +705,43 @@ func (pg *PostgresqlClient) PostGetByID(ctx context.Context, id uint64) (*models
 				Msg("error while querying post")
 		}
 
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	log.Info().
-		Interface("post", post).
+		Interface("post", p).
 		Msg("post retrieved from DB")
 
-	return &models.Post{
-		ID:      id,
-		Title:   post.Title,
-		Content: post.Content,
-		UserID:  post.UserID,
-	}, err
+	return toPost(p), err
 }
 
-func (pg *PostgresqlClient) PostDeleteByID(ctx context.Context, id uint64) error {
+func (pg *PostgresqlClient) PostDeleteByID(ctx context.Context, id uint64, opts models.DeleteOptions) error {
 	log := logger.
 		FromContext(ctx).
 		With().
 		Str("method", "postgresql.PostDeleteByID").
 		Logger()
 
-	err := pg.Post.DeleteOneID(id).Exec(ctx)
+	// Soft deleted, see `Purge`
+	del := pg.client(ctx).Post.UpdateOneID(id).
+		Where(post.DeletedAtIsNil()).
+		SetDeletedAt(time.Now().UTC().Truncate(time.Microsecond))
+	if opts.IfUpdatedAt != nil {
+		del = del.Where(post.UpdatedAt(opts.IfUpdatedAt.UTC()))
+	}
+
+	err := del.Exec(ctx)
 
 	if err != nil {
-		if !ent.IsNotFound(err) {
+		if opts.IfUpdatedAt != nil {
+			err = pg.postConditionalWriteError(ctx, id, err)
+		}
+		if !ent.IsNotFound(err) && !errors.Is(err, database.ErrPreconditionFailed) {
 			log.Err(err).
 				Msg("error while deleting post")
 		}
 
-		return err
+		return translateError(err)
 	}
 
 	log.Info().
@@ -322,56 +751,330 @@ func (pg *PostgresqlClient) PostDeleteByID(ctx context.Context, id uint64) error
 	return nil
 }
 
-func (pg *PostgresqlClient) PostUpdate(ctx context.Context, post models.PostUpdate) (*models.Post, error) {
+func (pg *PostgresqlClient) PostDeleteByUserID(ctx context.Context, userID uint64) (int, error) {
+	log := logger.
+		FromContext(ctx).
+		With().
+		Str("method", "postgresql.PostDeleteByUserID").
+		Logger()
+
+	var deleted int
+	err := pg.WithTx(ctx, func(ctx context.Context, _ database.DBRepository) error {
+		client := pg.client(ctx)
+
+		exists, err := client.User.Query().Where(user.ID(userID)).Exist(ctx)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return database.ErrNotFound
+		}
+
+		// Soft deleted, see `Purge`
+		deleted, err = client.Post.Update().
+			Where(post.UserID(userID), post.DeletedAtIsNil()).
+			SetDeletedAt(time.Now().UTC().Truncate(time.Microsecond)).
+			Save(ctx)
+
+		return err
+	})
+
+	if err != nil {
+		if !errors.Is(err, database.ErrNotFound) {
+			log.Err(err).
+				Msg("error while deleting posts")
+		}
+
+		return 0, translateError(err)
+	}
+
+	log.Info().
+		Uint64("user_id", userID).
+		Int("deleted", deleted).
+		Msg("posts deleted")
+
+	return deleted, nil
+}
+
+func (pg *PostgresqlClient) PostUpdate(ctx context.Context, update models.PostUpdate) (*models.Post, error) {
 	log := logger.
 		FromContext(ctx).
 		With().
 		Str("method", "postgresql.PostUpdate").
 		Logger()
 
-	p, err := pg.Post.UpdateOneID(*post.ID).
-		SetTitle(post.Title).
-		SetContent(post.Content).
-		Save(ctx)
+	upd := pg.client(ctx).Post.UpdateOneID(*update.ID).
+		Where(post.DeletedAtIsNil()).
+		SetTitle(update.Title).
+		SetContent(update.Content)
+	if update.IfUpdatedAt != nil {
+		upd = upd.Where(post.UpdatedAt(update.IfUpdatedAt.UTC()))
+	}
+
+	p, err := upd.Save(ctx)
 
 	if err != nil {
-		if !ent.IsNotFound(err) && !ent.IsConstraintError(err) {
+		if update.IfUpdatedAt != nil {
+			err = pg.postConditionalWriteError(ctx, *update.ID, err)
+		}
+		if !ent.IsNotFound(err) && !ent.IsConstraintError(err) && !errors.Is(err, database.ErrPreconditionFailed) {
 			log.Err(err).
 				Msg("error while updating post")
 		}
 
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	log.Info().
 		Interface("post", p).
 		Msg("post retrieved from DB")
 
-	return &models.Post{
-		ID:      p.ID,
-		Title:   p.Title,
-		Content: p.Content,
-		UserID:  p.UserID,
-	}, err
+	return toPost(p), err
 }
 
-// OTHER
-func (pg *PostgresqlClient) CreateDB(ctx context.Context, l *zerolog.Logger) error {
-	logger := l.With().
-		Str("method", "postgresql.CreateDB").
+func (pg *PostgresqlClient) PostPatch(ctx context.Context, patch models.PostPatch) (*models.Post, error) {
+	log := logger.
+		FromContext(ctx).
+		With().
+		Str("method", "postgresql.PostPatch").
 		Logger()
 
-	err := pg.Schema.Create(
-		ctx,
-		migrate.WithDropIndex(true),
-		migrate.WithDropColumn(true),
-	)
+	upd := pg.client(ctx).Post.UpdateOneID(*patch.ID).
+		Where(post.DeletedAtIsNil()).
+		SetNillableTitle(patch.Title).
+		SetNillableContent(patch.Content)
+	if patch.IfUpdatedAt != nil {
+		upd = upd.Where(post.UpdatedAt(patch.IfUpdatedAt.UTC()))
+	}
+
+	p, err := upd.Save(ctx)
+
 	if err != nil {
-		logger.Error().Err(err)
+		if patch.IfUpdatedAt != nil {
+			err = pg.postConditionalWriteError(ctx, *patch.ID, err)
+		}
+		if !ent.IsNotFound(err) && !ent.IsConstraintError(err) && !errors.Is(err, database.ErrPreconditionFailed) {
+			log.Err(err).
+				Msg("error while patching post")
+		}
+
+		return nil, translateError(err)
+	}
+
+	log.Info().
+		Interface("post", p).
+		Msg("post patched")
+
+	return toPost(p), err
+}
+
+// Posts of a deleted user stay deleted until the user is restored
+func (pg *PostgresqlClient) PostRestoreByID(ctx context.Context, id uint64) (*models.Post, error) {
+	log := logger.
+		FromContext(ctx).
+		With().
+		Str("method", "postgresql.PostRestoreByID").
+		Logger()
+
+	p, err := pg.client(ctx).Post.UpdateOneID(id).
+		Where(
+			post.DeletedAtNotNil(),
+			post.HasUserWith(user.DeletedAtIsNil()),
+		).
+		ClearDeletedAt().
+		Save(ctx)
+
+	if ent.IsNotFound(err) {
+		p, err = pg.client(ctx).Post.Get(withDeleted(ctx), id)
+		if err == nil && p.DeletedAt != nil {
+			err = database.ErrUserDeleted
+		}
+	}
+
+	if err != nil {
+		if !ent.IsNotFound(err) && !errors.Is(err, database.ErrUserDeleted) {
+			log.Err(err).
+				Msg("error while restoring post")
+		}
+
+		return nil, translateError(err)
+	}
+
+	log.Info().
+		Uint64("id", id).
+		Msg("post restored")
+
+	return toPost(p), nil
+}
+
+// Same as `userConditionalWriteError`, for posts
+func (pg *PostgresqlClient) postConditionalWriteError(ctx context.Context, id uint64, err error) error {
+	if !ent.IsNotFound(err) {
 		return err
 	}
 
-	return nil
+	exists, existsErr := pg.client(ctx).Post.Query().Where(post.ID(id)).Exist(ctx)
+	if existsErr != nil {
+		return existsErr
+	}
+	if exists {
+		return database.ErrPreconditionFailed
+	}
+
+	return err
+}
+
+// Applies the filters, ordering and pagination of `query` on top of `q`
+func postPage(ctx context.Context, q *ent.PostQuery, query models.PostQuery) (*models.Page[*models.Post], error) {
+	sort := models.WithIDTiebreaker(query.Sort)
+
+	q = q.
+		Where(postFilters(query)...).
+		Order(orderBy(sort)).
+		Limit(query.Limit + 1)
+
+	if query.After != nil {
+		values, err := postCursorValues(query.After)
+		if err != nil {
+			return nil, err
+		}
+
+		q = q.Where(afterCursor(sort, values))
+	}
+	if query.Include.User {
+		q = q.WithUser()
+	}
+
+	posts, err := q.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	page := models.NewPage(posts, query.Limit, func(p *ent.Post) *models.Cursor {
+		return postCursor(p, sort)
+	})
+
+	result := make([]*models.Post, 0, len(page.Items))
+	for _, p := range page.Items {
+		result = append(result, toPost(p))
+	}
+
+	return &models.Page[*models.Post]{
+		Items: result,
+		Next:  page.Next,
+	}, nil
+}
+
+func postFilters(query models.PostQuery) []predicate.Post {
+	filters := []predicate.Post{}
+
+	if query.UserID != nil {
+		filters = append(filters, post.UserID(*query.UserID))
+	}
+	if query.CreatedAfter != nil {
+		filters = append(filters, post.CreatedAtGT(query.CreatedAfter.UTC()))
+	}
+	if query.CreatedBefore != nil {
+		filters = append(filters, post.CreatedAtLT(query.CreatedBefore.UTC()))
+	}
+	if query.TitleContains != "" {
+		filters = append(filters, post.TitleContainsFold(query.TitleContains))
+	}
+
+	return filters
+}
+
+// Position of the post in the given ordering
+func postCursor(p *ent.Post, sort []models.SortField) *models.Cursor {
+	cursor := &models.Cursor{
+		ID:   p.ID,
+		Keys: map[string]string{},
+	}
+
+	for _, sf := range sort {
+		switch sf.Field {
+		case post.FieldTitle:
+			cursor.Keys[sf.Field] = p.Title
+		case post.FieldCreatedAt:
+			cursor.Keys[sf.Field] = p.CreatedAt.Format(time.RFC3339Nano)
+		case post.FieldUpdatedAt:
+			cursor.Keys[sf.Field] = p.UpdatedAt.Format(time.RFC3339Nano)
+		}
+	}
+
+	return cursor
+}
+
+// Typed values of a cursor built by `postCursor`
+func postCursorValues(cursor *models.Cursor) (map[string]any, error) {
+	values := map[string]any{
+		post.FieldID: cursor.ID,
+	}
+
+	for field, raw := range cursor.Keys {
+		switch field {
+		case post.FieldTitle:
+			values[field] = raw
+		case post.FieldCreatedAt, post.FieldUpdatedAt:
+			t, err := time.Parse(time.RFC3339Nano, raw)
+			if err != nil {
+				return nil, err
+			}
+			values[field] = t.UTC()
+		default:
+			return nil, fmt.Errorf("unknown cursor key %q", field)
+		}
+	}
+
+	return values, nil
+}
+
+// MAPPING
+// Edges are only mapped when they were eager loaded, timestamps are always UTC
+func toUser(u *ent.User) *models.User {
+	result := &models.User{
+		ID:        u.ID,
+		Name:      u.Name,
+		Email:     u.Email,
+		CreatedAt: u.CreatedAt.UTC(),
+		UpdatedAt: u.UpdatedAt.UTC(),
+	}
+
+	if posts, err := u.Edges.PostsOrErr(); err == nil {
+		result.Posts = make([]*models.Post, 0, len(posts))
+		for _, p := range posts {
+			result.Posts = append(result.Posts, toPost(p))
+		}
+	}
+
+	return result
+}
+
+func toPost(p *ent.Post) *models.Post {
+	result := &models.Post{
+		ID:        p.ID,
+		Title:     p.Title,
+		Content:   p.Content,
+		UserID:    p.UserID,
+		CreatedAt: p.CreatedAt.UTC(),
+		UpdatedAt: p.UpdatedAt.UTC(),
+	}
+
+	if u, err := p.Edges.UserOrErr(); err == nil {
+		result.User = toUser(u)
+	}
+
+	return result
+}
+
+func orderPostsByID(q *ent.PostQuery) {
+	q.Order(post.ByID())
+}
+
+// OTHER
+// Migrator of the versioned migrations of the database, see `cmd/migration`
+func (pg *PostgresqlClient) Migrator() (*migrations.Migrator, error) {
+	return migrations.New(pg.connection, pg.dialect)
 }
 
 func New(dns string, parentLogger *zerolog.Logger) *PostgresqlClient {
@@ -392,14 +1095,20 @@ func New(dns string, parentLogger *zerolog.Logger) *PostgresqlClient {
 	db.SetMaxOpenConns(100)
 	db.SetConnMaxLifetime(time.Hour)
 
-	drv := entsql.OpenDB(dialect.Postgres, db)
-	entClient := ent.NewClient(ent.Driver(drv))
-
 	logger.Info().
 		Msg("Successfully connected to DB")
 
+	return newClient(dialect.Postgres, db)
+}
+
+func newClient(dialectName string, db *sql.DB) *PostgresqlClient {
+	drv := entsql.OpenDB(dialectName, db)
+	entClient := ent.NewClient(ent.Driver(drv))
+	entClient.Intercept(softDeleteInterceptor())
+
 	return &PostgresqlClient{
-		entClient,
-		db,
+		Client:     entClient,
+		connection: db,
+		dialect:    dialectName,
 	}
 }
@@ -0,0 +1,331 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"entgo.io/ent/dialect"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/databasetest"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent/idempotencykey"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/logger"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
+)
+
+// Client backed by a fresh in-memory SQLite database, ent generates the same
+// queries for it as for Postgres
+func newTestClient(t *testing.T) (*PostgresqlClient, context.Context) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:?_pragma=foreign_keys(1)")
+	require.NoError(t, err)
+	// Every connection would get its own in-memory database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	l := zerolog.Nop()
+	ctx := logger.WithContext(context.Background(), &l)
+
+	client := newClient(dialect.SQLite, db)
+	migrator, err := client.Migrator()
+	require.NoError(t, err)
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	return client, ctx
+}
+
+func createUserWithPosts(t *testing.T, ctx context.Context, pg *PostgresqlClient, email string, posts int) *models.User {
+	t.Helper()
+
+	u, err := pg.UserCreate(ctx, models.User{Name: "John Doe", Email: email})
+	require.NoError(t, err)
+
+	for range posts {
+		_, err := pg.PostCreate(ctx, models.Post{Title: "coolio", Content: "coolest content", UserID: u.ID})
+		require.NoError(t, err)
+	}
+
+	return u
+}
+
+func countPosts(t *testing.T, ctx context.Context, pg *PostgresqlClient, userID uint64) int {
+	t.Helper()
+
+	page, err := pg.PostGetAll(ctx, models.PostQuery{
+		Pagination: models.Pagination{Limit: 100},
+		UserID:     &userID,
+	})
+	require.NoError(t, err)
+
+	return len(page.Items)
+}
+
+func Test_PostgresqlClient_Ping(t *testing.T) {
+	t.Run("should ping the database", func(t *testing.T) {
+		pg, ctx := newTestClient(t)
+
+		assert.NoError(t, pg.Ping(ctx))
+	})
+
+	t.Run("should give up once the context is done", func(t *testing.T) {
+		pg, ctx := newTestClient(t)
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		err := pg.Ping(ctx)
+		assert.ErrorIs(t, err, database.ErrUnavailable)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func Test_PostgresqlClient_UserDeleteByID(t *testing.T) {
+	t.Run("should delete a user without posts", func(t *testing.T) {
+		pg, ctx := newTestClient(t)
+		u := createUserWithPosts(t, ctx, pg, "johnnydoe@gmail.com", 0)
+
+		err := pg.UserDeleteByID(ctx, u.ID, models.DeleteOptions{})
+
+		assert.NoError(t, err)
+		_, err = pg.UserGetByID(ctx, u.ID, models.UserInclude{})
+		assert.ErrorIs(t, err, database.ErrNotFound)
+	})
+
+	t.Run("should refuse to delete a user with posts without cascade", func(t *testing.T) {
+		pg, ctx := newTestClient(t)
+		u := createUserWithPosts(t, ctx, pg, "johnnydoe@gmail.com", 2)
+
+		err := pg.UserDeleteByID(ctx, u.ID, models.DeleteOptions{})
+
+		var hasPosts *database.UserHasPostsError
+		require.ErrorAs(t, err, &hasPosts)
+		assert.Equal(t, 2, hasPosts.Posts)
+		// Nothing was deleted
+		_, err = pg.UserGetByID(ctx, u.ID, models.UserInclude{})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, countPosts(t, ctx, pg, u.ID))
+	})
+
+	t.Run("should not count posts that are already deleted", func(t *testing.T) {
+		pg, ctx := newTestClient(t)
+		u := createUserWithPosts(t, ctx, pg, "johnnydoe@gmail.com", 1)
+		page, err := pg.UserGetPosts(ctx, u.ID, models.PostQuery{Pagination: models.Pagination{Limit: 1}})
+		require.NoError(t, err)
+		require.NoError(t, pg.PostDeleteByID(ctx, page.Items[0].ID, models.DeleteOptions{}))
+
+		err = pg.UserDeleteByID(ctx, u.ID, models.DeleteOptions{})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("should delete the user and its posts with cascade", func(t *testing.T) {
+		pg, ctx := newTestClient(t)
+		u := createUserWithPosts(t, ctx, pg, "johnnydoe@gmail.com", 2)
+		other := createUserWithPosts(t, ctx, pg, "janedoe@gmail.com", 1)
+
+		err := pg.UserDeleteByID(ctx, u.ID, models.DeleteOptions{Cascade: true})
+
+		assert.NoError(t, err)
+		_, err = pg.UserGetByID(ctx, u.ID, models.UserInclude{})
+		assert.ErrorIs(t, err, database.ErrNotFound)
+		assert.Equal(t, 0, countPosts(t, ctx, pg, u.ID))
+		assert.Equal(t, 1, countPosts(t, ctx, pg, other.ID))
+	})
+
+	t.Run("should delete nothing when the precondition fails", func(t *testing.T) {
+		pg, ctx := newTestClient(t)
+		u := createUserWithPosts(t, ctx, pg, "johnnydoe@gmail.com", 2)
+		stale := u.UpdatedAt.Add(-time.Second)
+
+		err := pg.UserDeleteByID(ctx, u.ID, models.DeleteOptions{Cascade: true, IfUpdatedAt: &stale})
+
+		assert.ErrorIs(t, err, database.ErrPreconditionFailed)
+		assert.Equal(t, 2, countPosts(t, ctx, pg, u.ID))
+	})
+}
+
+func Test_PostgresqlClient_UserRestoreByID(t *testing.T) {
+	t.Run("should restore the posts deleted along with the user", func(t *testing.T) {
+		pg, ctx := newTestClient(t)
+		u := createUserWithPosts(t, ctx, pg, "johnnydoe@gmail.com", 3)
+		page, err := pg.UserGetPosts(ctx, u.ID, models.PostQuery{Pagination: models.Pagination{Limit: 1}})
+		require.NoError(t, err)
+		// Deleted on its own, before the user
+		require.NoError(t, pg.PostDeleteByID(ctx, page.Items[0].ID, models.DeleteOptions{}))
+		require.NoError(t, pg.UserDeleteByID(ctx, u.ID, models.DeleteOptions{Cascade: true}))
+
+		restored, err := pg.UserRestoreByID(ctx, u.ID)
+
+		require.NoError(t, err)
+		assert.Equal(t, u.ID, restored.ID)
+		assert.Equal(t, 2, countPosts(t, ctx, pg, u.ID))
+	})
+
+	t.Run("should return not found when user doesn't exist", func(t *testing.T) {
+		pg, ctx := newTestClient(t)
+
+		_, err := pg.UserRestoreByID(ctx, 1)
+
+		assert.ErrorIs(t, err, database.ErrNotFound)
+	})
+}
+
+func Test_PostgresqlClient_PurgeIdempotencyKeys(t *testing.T) {
+	t.Run("should only remove the expired keys", func(t *testing.T) {
+		pg, ctx := newTestClient(t)
+		l := zerolog.Nop()
+		for key, expiresAt := range map[string]time.Time{
+			"expired": time.Now().Add(-time.Minute),
+			"live":    time.Now().Add(time.Hour),
+		} {
+			_, err := pg.IdempotencyKeyClaim(ctx, models.IdempotencyRecord{Key: key, Fingerprint: "fingerprint", ExpiresAt: expiresAt})
+			require.NoError(t, err)
+		}
+
+		purged, err := pg.PurgeIdempotencyKeys(ctx, time.Now(), &l)
+		require.NoError(t, err)
+		remaining, err := pg.IdempotencyKey.Query().Select(idempotencykey.FieldKey).Strings(ctx)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, purged)
+		assert.Equal(t, []string{"live"}, remaining)
+	})
+}
+
+func Test_PostgresqlClient_Purge(t *testing.T) {
+	t.Run("should remove the rows deleted before a time in any time zone", func(t *testing.T) {
+		pg, ctx := newTestClient(t)
+		l := zerolog.Nop()
+		deleted := createUserWithPosts(t, ctx, pg, "johnnydoe@gmail.com", 2)
+		kept := createUserWithPosts(t, ctx, pg, "janedoe@gmail.com", 1)
+		require.NoError(t, pg.UserDeleteByID(ctx, deleted.ID, models.DeleteOptions{Cascade: true}))
+
+		users, posts, err := pg.Purge(ctx, time.Now().Add(time.Minute).In(time.FixedZone("UTC-7", -7*60*60)), &l)
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, users)
+		assert.Equal(t, 2, posts)
+		assert.Equal(t, 1, countPosts(t, ctx, pg, kept.ID))
+	})
+}
+
+func Test_postCursorValues(t *testing.T) {
+	t.Run("should compare the times of the cursor in UTC", func(t *testing.T) {
+		values, err := postCursorValues(&models.Cursor{
+			ID:   1,
+			Keys: map[string]string{"created_at": "2025-03-27T05:00:00-07:00"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2025, 3, 27, 12, 0, 0, 0, time.UTC), values["created_at"])
+	})
+}
+
+func Test_translateError(t *testing.T) {
+	t.Run("should report a duplicate email as a conflict on it", func(t *testing.T) {
+		pg, ctx := newTestClient(t)
+		createUserWithPosts(t, ctx, pg, "johnnydoe@gmail.com", 0)
+
+		_, err := pg.UserCreate(ctx, models.User{Name: "Johnny", Email: "johnnydoe@gmail.com"})
+
+		var conflict *database.ConflictError
+		require.ErrorAs(t, err, &conflict)
+		assert.ErrorIs(t, err, database.ErrConflict)
+		assert.Equal(t, "email", conflict.Field)
+	})
+
+	t.Run("should report a missing resource as not found", func(t *testing.T) {
+		pg, ctx := newTestClient(t)
+
+		_, err := pg.PostGetByID(ctx, 1, models.PostInclude{})
+
+		assert.ErrorIs(t, err, database.ErrNotFound)
+	})
+
+	t.Run("should report a missing referenced resource as a foreign key error", func(t *testing.T) {
+		pg, ctx := newTestClient(t)
+
+		_, err := pg.Post.Create().
+			SetTitle("coolio").
+			SetContent("coolest content").
+			SetUserID(1).
+			Save(ctx)
+
+		assert.ErrorIs(t, translateError(err), database.ErrForeignKey)
+	})
+
+	t.Run("should report any other failure as unavailable", func(t *testing.T) {
+		pg, ctx := newTestClient(t)
+		require.NoError(t, pg.Connection().Close())
+
+		_, err := pg.UserGetAll(ctx, models.UserQuery{Pagination: models.Pagination{Limit: 1}})
+
+		assert.ErrorIs(t, err, database.ErrUnavailable)
+	})
+
+	t.Run("should keep errors of the database package as is", func(t *testing.T) {
+		hasPosts := &database.UserHasPostsError{Posts: 1}
+
+		assert.Equal(t, database.ErrPreconditionFailed, translateError(database.ErrPreconditionFailed))
+		assert.Equal(t, hasPosts, translateError(hasPosts))
+		assert.Nil(t, translateError(nil))
+	})
+
+	t.Run("should tell the conflicting field from the Postgres constraint", func(t *testing.T) {
+		err := fmt.Errorf("wrapped: %w", &pgconn.PgError{Code: "23505", TableName: "users", ConstraintName: "users_email_key"})
+
+		assert.Equal(t, "email", conflictField(err))
+		assert.Equal(t, "", conflictField(errors.New("constraint failed")))
+	})
+}
+
+func Test_NewSQLite(t *testing.T) {
+	t.Run("should keep the data in the database file", func(t *testing.T) {
+		l := zerolog.Nop()
+		ctx := logger.WithContext(context.Background(), &l)
+		dsn := "file:" + filepath.Join(t.TempDir(), "challenge.db") + "?_pragma=foreign_keys(1)"
+
+		first := NewSQLite(dsn, &l)
+		migrator, err := first.Migrator()
+		require.NoError(t, err)
+		_, err = migrator.Up(ctx)
+		require.NoError(t, err)
+		u := createUserWithPosts(t, ctx, first, "johnnydoe@gmail.com", 1)
+		require.NoError(t, first.Close())
+
+		second := NewSQLite(dsn, &l)
+		t.Cleanup(func() {
+			second.Close()
+		})
+		migrator, err = second.Migrator()
+		require.NoError(t, err)
+		pending, err := migrator.Pending(ctx)
+		require.NoError(t, err)
+		found, err := second.UserGetByID(ctx, u.ID, models.UserInclude{Posts: true})
+
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+		assert.Equal(t, u.Email, found.Email)
+		assert.Len(t, found.Posts, 1)
+	})
+}
+
+func Test_PostgresqlClient_Contract(t *testing.T) {
+	databasetest.RunContract(t, func(t *testing.T) database.DBRepository {
+		pg, _ := newTestClient(t)
+		return pg
+	})
+}
@@ -0,0 +1,87 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent/post"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent/user"
+)
+
+type ctxKeyWithDeleted struct{}
+
+// Lets the queries run with the returned context see soft deleted rows
+func withDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeyWithDeleted{}, true)
+}
+
+// Hides soft deleted rows from every query, including eager loaded edges and
+// traversals like `QueryPosts`.
+//
+// Interceptors only apply to queries, updates and deletes must exclude soft
+// deleted rows themselves.
+func softDeleteInterceptor() ent.Interceptor {
+	return ent.TraverseFunc(func(ctx context.Context, q ent.Query) error {
+		if skip, _ := ctx.Value(ctxKeyWithDeleted{}).(bool); skip {
+			return nil
+		}
+
+		switch q := q.(type) {
+		case *ent.UserQuery:
+			q.Where(user.DeletedAtIsNil())
+		case *ent.PostQuery:
+			q.Where(post.DeletedAtIsNil())
+		}
+
+		return nil
+	})
+}
+
+// Permanently removes the users and posts soft deleted before `before`, along
+// with the posts of those users. Returns how many of each were removed.
+func (pg *PostgresqlClient) Purge(ctx context.Context, before time.Time, l *zerolog.Logger) (users int, posts int, err error) {
+	before = before.UTC()
+	logger := l.With().
+		Str("method", "postgresql.Purge").
+		Time("before", before).
+		Logger()
+
+	err = pg.WithTx(ctx, func(ctx context.Context, _ database.DBRepository) error {
+		client := pg.client(ctx)
+
+		posts, err = client.Post.Delete().
+			Where(post.Or(
+				post.DeletedAtLT(before),
+				post.HasUserWith(user.DeletedAtLT(before)),
+			)).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("purging posts: %w", err)
+		}
+
+		users, err = client.User.Delete().
+			Where(user.DeletedAtLT(before)).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("purging users: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("error purging soft deleted rows")
+		return 0, 0, err
+	}
+
+	logger.Info().
+		Int("users", users).
+		Int("posts", posts).
+		Msg("purged soft deleted rows")
+
+	return users, posts, nil
+}
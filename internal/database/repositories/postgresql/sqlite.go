@@ -0,0 +1,36 @@
+package postgresql
+
+import (
+	"database/sql"
+
+	"entgo.io/ent/dialect"
+	"github.com/rs/zerolog"
+	_ "modernc.org/sqlite"
+)
+
+// Client backed by the SQLite database at `dsn` instead of Postgres. ent
+// generates the same queries for both, so it behaves the same way, but with
+// a single writer at a time.
+func NewSQLite(dsn string, parentLogger *zerolog.Logger) *PostgresqlClient {
+	logger := parentLogger.
+		With().
+		Str("method", "postgresql/NewSQLite").
+		Logger()
+
+	logger.Info().
+		Msg("Opening SQLite database")
+
+	db, err := sql.Open("sqlite", dsn)
+
+	if err != nil {
+		logger.Panic().AnErr("error", err).Msg("Failed to open DB connection")
+	}
+	// SQLite locks the whole file on write, concurrent writers would only
+	// fail with `SQLITE_BUSY`
+	db.SetMaxOpenConns(1)
+
+	logger.Info().
+		Msg("Successfully opened SQLite database")
+
+	return newClient(dialect.SQLite, db)
+}
@@ -0,0 +1,70 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent"
+)
+
+type ctxKeyTx struct{}
+
+// Runs `fn` in an ent transaction, committed when it returns nil and rolled
+// back otherwise, panics included. The context given to `fn` carries the
+// transaction, every call made with it joins the transaction.
+func (pg *PostgresqlClient) WithTx(ctx context.Context, fn database.TxFunc) (err error) {
+	tx := pg.tx
+	if fromCtx, ok := ctx.Value(ctxKeyTx{}).(*ent.Tx); ok {
+		tx = fromCtx
+	}
+	if tx != nil {
+		return fn(context.WithValue(ctx, ctxKeyTx{}, tx), pg.inTx(tx))
+	}
+
+	tx, err = pg.Client.Tx(ctx)
+	if err != nil {
+		return translateError(err)
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			tx.Rollback()
+			panic(v)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, ctxKeyTx{}, tx), pg.inTx(tx)); err != nil {
+		return rollback(tx, err)
+	}
+
+	return translateError(tx.Commit())
+}
+
+// Rolls `tx` back, keeping `err` as the cause
+func rollback(tx *ent.Tx, err error) error {
+	if rerr := tx.Rollback(); rerr != nil {
+		return fmt.Errorf("%w: rolling back: %v", err, rerr)
+	}
+
+	return err
+}
+
+// Client to run the queries made with `ctx` on, the one of its transaction
+// if any
+func (pg *PostgresqlClient) client(ctx context.Context) *ent.Client {
+	if tx, ok := ctx.Value(ctxKeyTx{}).(*ent.Tx); ok {
+		return tx.Client()
+	}
+
+	return pg.Client
+}
+
+// Repository running every call in `tx`
+func (pg *PostgresqlClient) inTx(tx *ent.Tx) *PostgresqlClient {
+	return &PostgresqlClient{
+		Client:     tx.Client(),
+		connection: pg.connection,
+		dialect:    pg.dialect,
+		tx:         tx,
+	}
+}
@@ -6,13 +6,16 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 	"io"
 	"time"
 )
 
 type ctxKeyLogger struct{}
+type ctxKeyRequestID struct{}
 
 var loggerKey = ctxKeyLogger{}
+var requestIDKey = ctxKeyRequestID{}
 
 func FromContext(ctx context.Context) *zerolog.Logger {
 	l, ok := ctx.Value(loggerKey).(*zerolog.Logger)
@@ -33,14 +36,21 @@ var MiddlewareNowGenerator NowFunc = time.Now
 func NewMiddleware(baseLogger *zerolog.Logger) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		start := MiddlewareNowGenerator()
+		requestID := MiddlewareRequestIDGenerator()
 
-		reqLogger := baseLogger.With().
+		reqLoggerContext := baseLogger.With().
 			Str("method", ctx.Request.Method).
 			Str("path", ctx.Request.URL.Path).
-			Str("requestID", MiddlewareRequestIDGenerator()).
+			Str("requestID", requestID).
 			Str("client_ip", ctx.ClientIP()).
-			Str("user_agent", ctx.Request.UserAgent()).
-			Logger()
+			Str("user_agent", ctx.Request.UserAgent())
+		// Ties the logs to the trace of the request, when it is traced
+		if span := trace.SpanContextFromContext(ctx.Request.Context()); span.IsValid() {
+			reqLoggerContext = reqLoggerContext.
+				Str("trace_id", span.TraceID().String()).
+				Str("span_id", span.SpanID().String())
+		}
+		reqLogger := reqLoggerContext.Logger()
 
 		// Preparing to capture the response buffer
 		respBuf := new(bytes.Buffer)
@@ -52,12 +62,24 @@ func NewMiddleware(baseLogger *zerolog.Logger) gin.HandlerFunc {
 
 		// Inject logger
 		ctxWithLogger := WithContext(ctx.Request.Context(), &reqLogger)
+		ctxWithLogger = WithRequestID(ctxWithLogger, requestID)
 		ctx.Request = ctx.Request.WithContext(ctxWithLogger)
 
 		var requestBody []byte
 		if ctx.Request.Body != nil {
-			requestBody, _ = io.ReadAll(ctx.Request.Body)
-			ctx.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+			var readErr error
+			requestBody, readErr = io.ReadAll(ctx.Request.Body)
+			var body io.Reader = bytes.NewReader(requestBody)
+			if readErr != nil {
+				// Handlers still get the error after what could be read, e.g.
+				// for a body over the size limit
+				body = io.MultiReader(body, failingReader{readErr})
+			} else {
+				// Where `ShouldBindBodyWith` looks for the body before reading
+				// it, so it is only held in memory once
+				ctx.Set(gin.BodyBytesKey, requestBody)
+			}
+			ctx.Request.Body = io.NopCloser(body)
 		}
 
 		builder := reqLogger.Info().
@@ -97,3 +119,23 @@ func NewMiddleware(baseLogger *zerolog.Logger) gin.HandlerFunc {
 func WithContext(ctx context.Context, logger *zerolog.Logger) context.Context {
 	return context.WithValue(ctx, loggerKey, logger)
 }
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// Empty when the request didn't go through the middleware
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+
+	return requestID
+}
+
+// Fails every read with `err`
+type failingReader struct {
+	err error
+}
+
+func (r failingReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
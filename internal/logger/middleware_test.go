@@ -3,6 +3,7 @@ package logger
 import (
 	"bytes"
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -11,6 +12,7 @@ import (
 	"github.com/gkampitakis/go-snaps/snaps"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func Test_middleware_NewMiddleware(t *testing.T) {
@@ -52,6 +54,58 @@ func Test_middleware_NewMiddleware(t *testing.T) {
 		assert.Equal(t, http.StatusNoContent, w.Code, "code should be 204")
 		snaps.MatchSnapshot(t, logBuf.String())
 	})
+
+	t.Run("should add the trace and span IDs when the request is traced", func(t *testing.T) {
+		logBuf.Reset()
+		span := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+			SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+			TraceFlags: trace.FlagsSampled,
+		})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(trace.ContextWithSpanContext(req.Context(), span))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Contains(t, logBuf.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7"`)
+	})
+
+	t.Run("should pass on the error reading the request body", func(t *testing.T) {
+		var body []byte
+		var readErr error
+		router.PUT("/", func(ctx *gin.Context) {
+			body, readErr = io.ReadAll(ctx.Request.Body)
+			ctx.Status(http.StatusNoContent)
+		})
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`Hey`))
+		req.Body = http.MaxBytesReader(httptest.NewRecorder(), req.Body, 2)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, "He", string(body))
+		var tooLargeErr *http.MaxBytesError
+		assert.ErrorAs(t, readErr, &tooLargeErr)
+	})
+
+	t.Run("should read the request body once, for the handlers to bind", func(t *testing.T) {
+		var bound map[string]string
+		var bindErr error
+		router.PATCH("/", func(ctx *gin.Context) {
+			// Would find nothing left to read if it wasn't cached
+			ctx.Request.Body = io.NopCloser(strings.NewReader(""))
+			bindErr = ctx.ShouldBindBodyWithJSON(&bound)
+			ctx.Status(http.StatusNoContent)
+		})
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"name":"Johnny"}`))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.NoError(t, bindErr)
+		assert.Equal(t, map[string]string{"name": "Johnny"}, bound)
+	})
 }
 
 func Test_FromContext(t *testing.T) {
@@ -93,3 +147,15 @@ func Test_WithContext(t *testing.T) {
 		assert.Same(t, &logger, newLogger, "loggers are not the same")
 	})
 }
+
+func Test_RequestIDFromContext(t *testing.T) {
+	t.Run("should return the request ID set in the context", func(t *testing.T) {
+		ctx := WithRequestID(context.Background(), "1234")
+
+		assert.Equal(t, "1234", RequestIDFromContext(ctx))
+	})
+
+	t.Run("should return an empty string without a request ID", func(t *testing.T) {
+		assert.Equal(t, "", RequestIDFromContext(context.Background()))
+	})
+}
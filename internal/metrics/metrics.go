@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
+)
+
+// Prefix of every metric of the API
+const namespace = "challenge"
+
+// Route of the requests that matched none, so unknown paths don't each get
+// their own series
+const unmatchedRoute = "unmatched"
+
+// Metrics of the API, exposed in the Prometheus text format by `Handler`
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	callDuration    *prometheus.HistogramVec
+	callErrors      *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests served, by method, route template and status.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to serve HTTP requests, by method, route template and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		callDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_call_duration_seconds",
+			Help:      "Time taken by the calls to the repository, by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		callErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "repository_call_errors_total",
+			Help:      "Calls to the repository that failed unexpectedly, e.g. with the database unavailable, by method. Not found, conflicts and the like are not counted.",
+		}, []string{"method"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.callDuration,
+		m.callErrors,
+	)
+
+	return m
+}
+
+// Exports the connection pool statistics of `db`, e.g. open and in use
+// connections or the time spent waiting for one
+func (m *Metrics) RegisterDBStats(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Counts the requests and their latency, labeled by the route template that
+// matched instead of the path, e.g. `/users/:id`, to keep the number of
+// series bounded
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(ctx.Writer.Status())
+
+		m.requests.WithLabelValues(ctx.Request.Method, route, status).Inc()
+		m.requestDuration.WithLabelValues(ctx.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Records a call to the repository `method` started at `start`. Only the
+// errors that are failures of the database count, not a not found or a failed
+// precondition the API answers with a 4xx
+func (m *Metrics) observeCall(method string, start time.Time, err error) {
+	m.callDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if database.IsUnexpected(err) {
+		m.callErrors.WithLabelValues(method).Inc()
+	}
+}
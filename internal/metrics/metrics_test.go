@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/inmemory"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
+)
+
+// Number of observations of a histogram
+func sampleCount(t *testing.T, o prometheus.Observer) uint64 {
+	t.Helper()
+
+	var metric dto.Metric
+	require.NoError(t, o.(prometheus.Metric).Write(&metric))
+
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func Test_Metrics_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := New()
+
+	router := gin.New()
+	router.Use(m.Middleware())
+	router.Use(gin.Recovery())
+	router.GET("/users/:id", func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+	router.GET("/panic", func(ctx *gin.Context) {
+		panic("You've met a terrible fate, haven't you?")
+	})
+	router.GET("/metrics", gin.WrapH(m.Handler()))
+
+	for _, path := range []string{"/users/1", "/users/2", "/panic", "/unknown/1", "/unknown/2"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	t.Run("should label the requests by route template", func(t *testing.T) {
+		assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues(http.MethodGet, "/users/:id", "204")))
+		assert.Equal(t, uint64(2), sampleCount(t, m.requestDuration.WithLabelValues(http.MethodGet, "/users/:id", "204")))
+	})
+
+	t.Run("should count panics as 500", func(t *testing.T) {
+		assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues(http.MethodGet, "/panic", "500")))
+	})
+
+	t.Run("should gather the requests matching no route under one label", func(t *testing.T) {
+		assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues(http.MethodGet, "unmatched", "404")))
+	})
+
+	t.Run("should expose the metrics in the Prometheus text format", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+		assert.Contains(t, w.Body.String(), `challenge_http_requests_total{method="GET",route="/users/:id",status="204"} 2`)
+		assert.Contains(t, w.Body.String(), "go_goroutines")
+	})
+}
+
+func Test_Metrics_Repository(t *testing.T) {
+	m := New()
+	db := m.Repository(inmemory.New(time.Now))
+	ctx := context.Background()
+
+	t.Run("should measure every call", func(t *testing.T) {
+		_, err := db.UserCreate(ctx, models.User{Name: "John Doe", Email: "johnnydoe@gmail.com"})
+		require.NoError(t, err)
+		_, err = db.UserGetByID(ctx, 1, models.UserInclude{})
+		require.NoError(t, err)
+
+		assert.Equal(t, uint64(1), sampleCount(t, m.callDuration.WithLabelValues("UserCreate")))
+		assert.Equal(t, uint64(1), sampleCount(t, m.callDuration.WithLabelValues("UserGetByID")))
+		assert.Equal(t, 0.0, testutil.ToFloat64(m.callErrors.WithLabelValues("UserCreate")))
+	})
+
+	t.Run("should count the calls that fail", func(t *testing.T) {
+		oldUserGetByIDFn := inmemory.InMemoryUserGetByIDFn
+		defer func() {
+			inmemory.InMemoryUserGetByIDFn = oldUserGetByIDFn
+		}()
+		inmemory.InMemoryUserGetByIDFn = func(ctx context.Context, id uint64, include models.UserInclude) (*models.User, error) {
+			return nil, database.ErrUnavailable
+		}
+
+		_, err := db.UserGetByID(ctx, 1, models.UserInclude{})
+		require.ErrorIs(t, err, database.ErrUnavailable)
+
+		assert.Equal(t, 1.0, testutil.ToFloat64(m.callErrors.WithLabelValues("UserGetByID")))
+	})
+
+	t.Run("should not count the calls answered with an expected error", func(t *testing.T) {
+		_, err := db.UserGetByID(ctx, 42, models.UserInclude{})
+		require.ErrorIs(t, err, database.ErrNotFound)
+		err = db.UserDeleteByID(ctx, 1, models.DeleteOptions{IfUpdatedAt: &time.Time{}})
+		require.ErrorIs(t, err, database.ErrPreconditionFailed)
+
+		assert.Equal(t, 1.0, testutil.ToFloat64(m.callErrors.WithLabelValues("UserGetByID")))
+		assert.Equal(t, 0.0, testutil.ToFloat64(m.callErrors.WithLabelValues("UserDeleteByID")))
+	})
+
+	t.Run("should measure the calls within a transaction", func(t *testing.T) {
+		err := db.WithTx(ctx, func(ctx context.Context, tx database.DBRepository) error {
+			_, err := tx.PostCreate(ctx, models.Post{Title: "coolio", Content: "coolest content", UserID: 1})
+			if err != nil {
+				return err
+			}
+
+			return errors.New("something terrible happened")
+		})
+		require.Error(t, err)
+
+		assert.Equal(t, uint64(1), sampleCount(t, m.callDuration.WithLabelValues("PostCreate")))
+		assert.Equal(t, 1.0, testutil.ToFloat64(m.callErrors.WithLabelValues("WithTx")))
+	})
+}
+
+func Test_Metrics_RegisterDBStats(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	m := New()
+	m.RegisterDBStats(db, "sqlite")
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	for _, gauge := range []string{"go_sql_open_connections", "go_sql_in_use_connections", "go_sql_wait_duration_seconds_total"} {
+		assert.Contains(t, w.Body.String(), gauge+`{db_name="sqlite"}`)
+	}
+}
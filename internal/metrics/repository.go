@@ -0,0 +1,215 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
+)
+
+// Repository that records the latency and errors of every call to the one
+// it wraps
+type repository struct {
+	db      database.DBRepository
+	metrics *Metrics
+}
+
+// Wraps `db` so its calls are measured, see `repository_call_duration_seconds`
+// and `repository_call_errors_total`
+func (m *Metrics) Repository(db database.DBRepository) database.DBRepository {
+	return &repository{db: db, metrics: m}
+}
+
+// TRANSACTIONS
+// The calls made within the transaction are measured too, the transaction as
+// a whole is `WithTx`
+func (r *repository) WithTx(ctx context.Context, fn database.TxFunc) error {
+	start := time.Now()
+	err := r.db.WithTx(ctx, func(ctx context.Context, tx database.DBRepository) error {
+		return fn(ctx, r.metrics.Repository(tx))
+	})
+	r.metrics.observeCall("WithTx", start, err)
+
+	return err
+}
+
+// HEALTH
+func (r *repository) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := r.db.Ping(ctx)
+	r.metrics.observeCall("Ping", start, err)
+
+	return err
+}
+
+// USERS
+func (r *repository) UserCreate(ctx context.Context, user models.User) (*models.User, error) {
+	start := time.Now()
+	result, err := r.db.UserCreate(ctx, user)
+	r.metrics.observeCall("UserCreate", start, err)
+
+	return result, err
+}
+
+func (r *repository) UserCreateBulk(ctx context.Context, users []models.User, opts models.BulkOptions) ([]*models.User, error) {
+	start := time.Now()
+	result, err := r.db.UserCreateBulk(ctx, users, opts)
+	r.metrics.observeCall("UserCreateBulk", start, err)
+
+	return result, err
+}
+
+func (r *repository) UserGetAll(ctx context.Context, query models.UserQuery) (*models.Page[*models.User], error) {
+	start := time.Now()
+	result, err := r.db.UserGetAll(ctx, query)
+	r.metrics.observeCall("UserGetAll", start, err)
+
+	return result, err
+}
+
+func (r *repository) UserGetByID(ctx context.Context, id uint64, include models.UserInclude) (*models.User, error) {
+	start := time.Now()
+	result, err := r.db.UserGetByID(ctx, id, include)
+	r.metrics.observeCall("UserGetByID", start, err)
+
+	return result, err
+}
+
+func (r *repository) UserGetPosts(ctx context.Context, id uint64, query models.PostQuery) (*models.Page[*models.Post], error) {
+	start := time.Now()
+	result, err := r.db.UserGetPosts(ctx, id, query)
+	r.metrics.observeCall("UserGetPosts", start, err)
+
+	return result, err
+}
+
+func (r *repository) UserDeleteByID(ctx context.Context, id uint64, opts models.DeleteOptions) error {
+	start := time.Now()
+	err := r.db.UserDeleteByID(ctx, id, opts)
+	r.metrics.observeCall("UserDeleteByID", start, err)
+
+	return err
+}
+
+func (r *repository) UserUpdate(ctx context.Context, user models.UserUpdate) (*models.User, error) {
+	start := time.Now()
+	result, err := r.db.UserUpdate(ctx, user)
+	r.metrics.observeCall("UserUpdate", start, err)
+
+	return result, err
+}
+
+func (r *repository) UserPatch(ctx context.Context, patch models.UserPatch) (*models.User, error) {
+	start := time.Now()
+	result, err := r.db.UserPatch(ctx, patch)
+	r.metrics.observeCall("UserPatch", start, err)
+
+	return result, err
+}
+
+func (r *repository) UserRestoreByID(ctx context.Context, id uint64) (*models.User, error) {
+	start := time.Now()
+	result, err := r.db.UserRestoreByID(ctx, id)
+	r.metrics.observeCall("UserRestoreByID", start, err)
+
+	return result, err
+}
+
+// POSTS
+func (r *repository) PostCreate(ctx context.Context, post models.Post) (*models.Post, error) {
+	start := time.Now()
+	result, err := r.db.PostCreate(ctx, post)
+	r.metrics.observeCall("PostCreate", start, err)
+
+	return result, err
+}
+
+func (r *repository) PostCreateBulk(ctx context.Context, posts []models.Post, opts models.BulkOptions) ([]*models.Post, error) {
+	start := time.Now()
+	result, err := r.db.PostCreateBulk(ctx, posts, opts)
+	r.metrics.observeCall("PostCreateBulk", start, err)
+
+	return result, err
+}
+
+func (r *repository) PostGetAll(ctx context.Context, query models.PostQuery) (*models.Page[*models.Post], error) {
+	start := time.Now()
+	result, err := r.db.PostGetAll(ctx, query)
+	r.metrics.observeCall("PostGetAll", start, err)
+
+	return result, err
+}
+
+func (r *repository) PostGetByID(ctx context.Context, id uint64, include models.PostInclude) (*models.Post, error) {
+	start := time.Now()
+	result, err := r.db.PostGetByID(ctx, id, include)
+	r.metrics.observeCall("PostGetByID", start, err)
+
+	return result, err
+}
+
+func (r *repository) PostDeleteByID(ctx context.Context, id uint64, opts models.DeleteOptions) error {
+	start := time.Now()
+	err := r.db.PostDeleteByID(ctx, id, opts)
+	r.metrics.observeCall("PostDeleteByID", start, err)
+
+	return err
+}
+
+func (r *repository) PostDeleteByUserID(ctx context.Context, userID uint64) (int, error) {
+	start := time.Now()
+	result, err := r.db.PostDeleteByUserID(ctx, userID)
+	r.metrics.observeCall("PostDeleteByUserID", start, err)
+
+	return result, err
+}
+
+func (r *repository) PostUpdate(ctx context.Context, post models.PostUpdate) (*models.Post, error) {
+	start := time.Now()
+	result, err := r.db.PostUpdate(ctx, post)
+	r.metrics.observeCall("PostUpdate", start, err)
+
+	return result, err
+}
+
+func (r *repository) PostPatch(ctx context.Context, patch models.PostPatch) (*models.Post, error) {
+	start := time.Now()
+	result, err := r.db.PostPatch(ctx, patch)
+	r.metrics.observeCall("PostPatch", start, err)
+
+	return result, err
+}
+
+func (r *repository) PostRestoreByID(ctx context.Context, id uint64) (*models.Post, error) {
+	start := time.Now()
+	result, err := r.db.PostRestoreByID(ctx, id)
+	r.metrics.observeCall("PostRestoreByID", start, err)
+
+	return result, err
+}
+
+// IDEMPOTENCY
+func (r *repository) IdempotencyKeyClaim(ctx context.Context, record models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	start := time.Now()
+	result, err := r.db.IdempotencyKeyClaim(ctx, record)
+	r.metrics.observeCall("IdempotencyKeyClaim", start, err)
+
+	return result, err
+}
+
+func (r *repository) IdempotencyKeyComplete(ctx context.Context, record models.IdempotencyRecord) error {
+	start := time.Now()
+	err := r.db.IdempotencyKeyComplete(ctx, record)
+	r.metrics.observeCall("IdempotencyKeyComplete", start, err)
+
+	return err
+}
+
+func (r *repository) IdempotencyKeyRelease(ctx context.Context, key string) error {
+	start := time.Now()
+	err := r.db.IdempotencyKeyRelease(ctx, key)
+	r.metrics.observeCall("IdempotencyKeyRelease", start, err)
+
+	return err
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"net/http"
+	"time"
+)
+
+// IdempotencyRecord is a request sent with an `Idempotency-Key` header and,
+// once processed, the response replayed to its retries.
+type IdempotencyRecord struct {
+	Key string
+	// Hash of the request, its retries must have the same
+	Fingerprint string
+	// Zero while the request is being processed
+	Status    int
+	Header    http.Header
+	Body      []byte
+	ExpiresAt time.Time
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// DeleteOptions tunes how a resource is deleted.
+type DeleteOptions struct {
+	// When set, the resource is only deleted if its `updated_at` still
+	// matches, i.e. nobody modified it in the meantime
+	IfUpdatedAt *time.Time
+	// Deleting a user who owns posts deletes them too, otherwise it fails
+	Cascade bool
+}
+
+// BulkOptions tunes how a batch of resources is created.
+type BulkOptions struct {
+	// Creates the items that can be created even when others fail,
+	// otherwise a single failure creates none
+	Partial bool
+}
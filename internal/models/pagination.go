@@ -0,0 +1,59 @@
+package models
+
+// Pagination describes a keyset page request: at most `Limit` items placed
+// after the position marked by `After` (or from the start when nil).
+type Pagination struct {
+	Limit int
+	After *Cursor
+}
+
+// Cursor marks the position of the last item of a page. When the listing is
+// sorted by other fields than `id`, their values are kept in `Keys` (by field
+// name) so the next page can resume from the same spot.
+type Cursor struct {
+	ID   uint64            `json:"id"`
+	Keys map[string]string `json:"keys,omitempty"`
+}
+
+// SortField is one of the criteria of a listing order.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// WithIDTiebreaker appends an ascending `id` to the ordering, unless already
+// present, so that every row has a unique position for keyset pagination.
+func WithIDTiebreaker(sort []SortField) []SortField {
+	for _, sf := range sort {
+		if sf.Field == "id" {
+			return sort
+		}
+	}
+
+	result := make([]SortField, 0, len(sort)+1)
+	result = append(result, sort...)
+
+	return append(result, SortField{Field: "id"})
+}
+
+// Page is a slice of results plus the cursor of the next page, which is nil
+// when there is nothing left to fetch.
+type Page[T any] struct {
+	Items []T
+	Next  *Cursor
+}
+
+// NewPage builds a page out of a query that fetched up to `limit + 1` items,
+// the extra item only being used to know if there is a next page.
+func NewPage[T any](items []T, limit int, cursor func(T) *Cursor) *Page[T] {
+	if len(items) <= limit {
+		return &Page[T]{Items: items}
+	}
+
+	items = items[:limit]
+
+	return &Page[T]{
+		Items: items,
+		Next:  cursor(items[len(items)-1]),
+	}
+}
@@ -1,14 +1,66 @@
 package models
 
+import "time"
+
 type Post struct {
 	ID      uint64 `json:"id"`
 	Title   string `json:"title" binding:"required"`
 	Content string `json:"content" binding:"required"`
 	UserID  uint64 `json:"user_id" binding:"required"`
+	// Set by the database, also the version of the post for optimistic
+	// concurrency
+	CreatedAt time.Time `json:"created_at" binding:"-"`
+	UpdatedAt time.Time `json:"updated_at" binding:"-"`
+	// Only set when requested with `?include=user`
+	User *User `json:"user,omitempty" binding:"-"`
+}
+
+// UserPost is a post created under `/users/:id/posts`, where the path
+// provides the user.
+type UserPost struct {
+	Title   string `json:"title" binding:"required"`
+	Content string `json:"content" binding:"required"`
 }
 
 type PostUpdate struct {
 	ID      *uint64 `json:"id"`
 	Title   string  `json:"title" binding:"required"`
 	Content string  `json:"content" binding:"required"`
+	// When set, only updates if `updated_at` still matches
+	IfUpdatedAt *time.Time `json:"-"`
+}
+
+// PostPatch is a JSON Merge Patch (RFC 7396) of a post, fields left nil are
+// not modified.
+type PostPatch struct {
+	ID      *uint64 `json:"id"`
+	Title   *string `json:"title" binding:"omitnil,min=1"`
+	Content *string `json:"content" binding:"omitnil,min=1"`
+	// When set, only updates if `updated_at` still matches
+	IfUpdatedAt *time.Time `json:"-"`
+}
+
+// Fields posts can be sorted by
+const (
+	PostFieldID        = "id"
+	PostFieldTitle     = "title"
+	PostFieldCreatedAt = "created_at"
+	PostFieldUpdatedAt = "updated_at"
+)
+
+// PostInclude lists the related resources to embed in posts.
+type PostInclude struct {
+	User bool
+}
+
+// PostQuery holds the filters, ordering and pagination of a post listing.
+// Nil or empty filters are not applied.
+type PostQuery struct {
+	Pagination
+	UserID        *uint64
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	TitleContains string
+	Sort          []SortField
+	Include       PostInclude
 }
@@ -1,13 +1,45 @@
 package models
 
+import "time"
+
 type User struct {
 	ID    uint64 `json:"id"`
 	Name  string `json:"name"  binding:"required"`
 	Email string `json:"email" binding:"required,email"`
+	// Set by the database, also the version of the user for optimistic
+	// concurrency
+	CreatedAt time.Time `json:"created_at" binding:"-"`
+	UpdatedAt time.Time `json:"updated_at" binding:"-"`
+	// Only set when requested with `?include=posts`, empty (not nil) when
+	// the user has no posts
+	Posts []*Post `json:"posts,omitzero" binding:"-"`
 }
 
 type UserUpdate struct {
 	ID    *uint64 `json:"id"`
 	Name  string  `json:"name"  binding:"required"`
 	Email string  `json:"email" binding:"required,email"`
+	// When set, only updates if `updated_at` still matches
+	IfUpdatedAt *time.Time `json:"-"`
+}
+
+// UserPatch is a JSON Merge Patch (RFC 7396) of a user, fields left nil are
+// not modified.
+type UserPatch struct {
+	ID    *uint64 `json:"id"`
+	Name  *string `json:"name"  binding:"omitnil,min=1"`
+	Email *string `json:"email" binding:"omitnil,email"`
+	// When set, only updates if `updated_at` still matches
+	IfUpdatedAt *time.Time `json:"-"`
+}
+
+// UserInclude lists the related resources to embed in users.
+type UserInclude struct {
+	Posts bool
+}
+
+// UserQuery holds the pagination of a user listing.
+type UserQuery struct {
+	Pagination
+	Include UserInclude
 }
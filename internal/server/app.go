@@ -1,22 +1,51 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/config"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/inmemory"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/migrations"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/logger"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/metrics"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/tracing"
 	"github.com/gin-gonic/gin"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/rs/zerolog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
+// How long the spans left get to be exported on shutdown
+const tracingFlushTimeout = 5 * time.Second
+
 type Application struct {
 	Router *gin.Engine
 	Logger *zerolog.Logger
 	Config *config.Config
-	DB     database.DBRepository
+	Users  database.UserRepository
+	Posts  database.PostRepository
+	Health database.HealthChecker
+	// Responses to requests sent with an `Idempotency-Key`
+	Idempotency database.IdempotencyRepository
+	Metrics     *metrics.Metrics
+	// Set once the API starts shutting down, failing the readiness check
+	shuttingDown *atomic.Bool
+	// Releases the database connections, nil when there are none
+	closeDB func() error
+	// Exports the spans left, nil when they are not exported
+	shutdownTracing func(context.Context) error
+	// Dependencies checked along with the database, see `RegisterHealthCheck`
+	healthChecks []healthCheck
 }
 
 func New() Application {
@@ -32,17 +61,191 @@ func New() Application {
 	r.RemoveExtraSlash = true
 
 	l := logger.New(c.IsDev)
+	m := metrics.New()
+
+	shutdownTracing, err := tracing.Setup(context.Background(), c.TracingEndpoint)
+	if err != nil {
+		l.Fatal().Err(err).Msg("Failed to set up tracing")
+	}
 
-	db := postgresql.New(c.DB.String(), l)
+	var db database.DBRepository
+	var closeDB func() error
+	var migrator *migrations.Migrator
+	// Database as named in the spans
+	dbSystem := "postgresql"
+	switch c.DB.Driver {
+	case config.DriverMemory:
+		db = inmemory.New(time.Now)
+		dbSystem = "memory"
+	case config.DriverSQLite:
+		client := postgresql.NewSQLite(c.DB.String(), l)
+		migrator = requireMigrated(client, l)
+		m.RegisterDBStats(client.Connection(), c.DB.Driver)
+		db = client
+		closeDB = client.Close
+		dbSystem = "sqlite"
+	default:
+		client := postgresql.New(c.DB.String(), l)
+		migrator = requireMigrated(client, l)
+		m.RegisterDBStats(client.Connection(), c.DB.Driver)
+		db = client
+		closeDB = client.Close
+	}
+	db = tracing.Repository(m.Repository(db), dbSystem)
 
-	return Application{
-		Router: r,
-		Logger: l,
-		Config: &c,
-		DB:     db,
+	a := Application{
+		Router:          r,
+		Logger:          l,
+		Config:          &c,
+		Users:           db,
+		Posts:           db,
+		Health:          db,
+		Idempotency:     db,
+		Metrics:         m,
+		shuttingDown:    &atomic.Bool{},
+		closeDB:         closeDB,
+		shutdownTracing: shutdownTracing,
+	}
+	if migrator != nil {
+		a.RegisterHealthCheck("migrations", migrationsCheck(migrator))
 	}
+
+	return a
 }
 
+// Refuses to start on a schema the code doesn't expect, returning the
+// migrator that checked it
+func requireMigrated(client *postgresql.PostgresqlClient, l *zerolog.Logger) *migrations.Migrator {
+	migrator, err := client.Migrator()
+	if err != nil {
+		l.Fatal().Err(err).Msg("Failed to load migrations")
+	}
+
+	pending, err := migrator.Pending(context.Background())
+	if err != nil {
+		l.Fatal().Err(err).Msg("Failed to check for pending migrations")
+	}
+	if len(pending) > 0 {
+		l.Fatal().
+			Strs("pending", migrationNames(pending)).
+			Msg("database has pending migrations, apply them with `go run ./cmd/migration up`")
+	}
+
+	return migrator
+}
+
+// Serves the API on `port` until a SIGTERM or SIGINT, then shuts it down
+// gracefully, see `serve`
 func (a *Application) Serve(port uint) error {
-	return a.Router.Run(fmt.Sprintf(":%d", a.Config.Port))
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return errors.Join(err, a.close())
+	}
+
+	var adminListener net.Listener
+	if a.Config.MetricsPort != 0 {
+		adminListener, err = net.Listen("tcp", fmt.Sprintf(":%d", a.Config.MetricsPort))
+		if err != nil {
+			listener.Close()
+			return errors.Join(err, a.close())
+		}
+	}
+
+	return a.serve(ctx, listener, adminListener)
+}
+
+// Serves the API on `listener`, and `/metrics` on `adminListener` unless nil,
+// until `ctx` is done. On shutdown the readiness check fails first, for
+// `Config.ShutdownDelay`, then no new connections are accepted and the
+// in-flight requests get up to `Config.ShutdownTimeout` to finish before the
+// database is closed. `/metrics` is served until the API is done
+func (a *Application) serve(ctx context.Context, listener net.Listener, adminListener net.Listener) error {
+	srv := &http.Server{
+		Handler:           a.Router,
+		ReadHeaderTimeout: a.Config.ReadHeaderTimeout,
+		ReadTimeout:       a.Config.ReadTimeout,
+		WriteTimeout:      a.Config.WriteTimeout,
+		IdleTimeout:       a.Config.IdleTimeout,
+	}
+	servers := []*http.Server{srv}
+	listeners := []net.Listener{listener}
+
+	if adminListener != nil {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", a.Metrics.Handler())
+		servers = append(servers, &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: a.Config.ReadHeaderTimeout,
+			ReadTimeout:       a.Config.ReadTimeout,
+			WriteTimeout:      a.Config.WriteTimeout,
+			IdleTimeout:       a.Config.IdleTimeout,
+		})
+		listeners = append(listeners, adminListener)
+	}
+
+	served := make(chan error, len(servers))
+	for i, s := range servers {
+		go func() {
+			served <- s.Serve(listeners[i])
+		}()
+
+		a.Logger.Info().
+			Str("address", listeners[i].Addr().String()).
+			Msg("Listening for requests")
+	}
+
+	select {
+	case err := <-served:
+		for _, s := range servers {
+			s.Close()
+		}
+		return errors.Join(err, a.close())
+	case <-ctx.Done():
+	}
+
+	a.shuttingDown.Store(true)
+	a.Logger.Info().
+		Dur("delay", a.Config.ShutdownDelay).
+		Msg("Shutting down, failing the readiness check")
+	time.Sleep(a.Config.ShutdownDelay)
+
+	a.Logger.Info().
+		Dur("timeout", a.Config.ShutdownTimeout).
+		Msg("Waiting for in-flight requests")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.Config.ShutdownTimeout)
+	defer cancel()
+
+	var err error
+	for _, s := range servers {
+		if shutdownErr := s.Shutdown(shutdownCtx); shutdownErr != nil {
+			err = errors.Join(err, fmt.Errorf("in-flight requests did not finish: %w", shutdownErr))
+			s.Close()
+		}
+	}
+
+	return errors.Join(err, a.close())
+}
+
+// Closes the database, then exports the spans left
+func (a *Application) close() error {
+	var err error
+	if a.closeDB != nil {
+		a.Logger.Info().
+			Msg("Closing the database connections")
+
+		err = a.closeDB()
+	}
+
+	if a.shutdownTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+		defer cancel()
+
+		err = errors.Join(err, a.shutdownTracing(ctx))
+	}
+
+	return err
 }
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/config"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/inmemory"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/logger"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/metrics"
+)
+
+func Test_Application_serve(t *testing.T) {
+	// Serves `/slow`, answering once `release` is closed, and `/health`
+	newApp := func(c config.Config, closeDB func() error) (*Application, chan struct{}, chan struct{}) {
+		l := zerolog.Nop()
+		db := inmemory.New(testClock)
+		a := &Application{
+			Router:       gin.New(),
+			Logger:       &l,
+			Config:       &c,
+			Health:       db,
+			Metrics:      metrics.New(),
+			shuttingDown: &atomic.Bool{},
+			closeDB:      closeDB,
+		}
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		a.Router.Use(logger.NewMiddleware(a.Logger))
+		a.Router.GET("/health", a.HealthCheck)
+		a.Router.GET("/slow", func(ctx *gin.Context) {
+			close(started)
+			<-release
+			ctx.Status(http.StatusNoContent)
+		})
+
+		return a, started, release
+	}
+
+	run := func(a *Application) (string, context.CancelFunc, chan error) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- a.serve(ctx, listener, nil)
+		}()
+
+		return "http://" + listener.Addr().String(), cancel, done
+	}
+
+	t.Run("should serve the metrics on the admin listener", func(t *testing.T) {
+		a, _, _ := newApp(config.Config{
+			ShutdownTimeout: time.Second,
+		}, nil)
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		adminListener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- a.serve(ctx, listener, adminListener)
+		}()
+
+		res, err := http.Get("http://" + adminListener.Addr().String() + "/metrics")
+		require.NoError(t, err)
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, string(body), "go_goroutines")
+
+		res, err = http.Get("http://" + listener.Addr().String() + "/metrics")
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		cancel()
+		assert.NoError(t, <-done)
+
+		_, err = http.Get("http://" + adminListener.Addr().String() + "/metrics")
+		assert.Error(t, err)
+	})
+
+	t.Run("should finish in-flight requests before closing the database", func(t *testing.T) {
+		closed := false
+		a, started, release := newApp(config.Config{
+			ShutdownDelay:   100 * time.Millisecond,
+			ShutdownTimeout: 5 * time.Second,
+		}, func() error {
+			closed = true
+			return nil
+		})
+		url, cancel, done := run(a)
+
+		slow := make(chan int, 1)
+		go func() {
+			res, err := http.Get(url + "/slow")
+			if err != nil {
+				slow <- 0
+				return
+			}
+			res.Body.Close()
+			slow <- res.StatusCode
+		}()
+		<-started
+
+		cancel()
+		require.Eventually(t, a.shuttingDown.Load, time.Second, time.Millisecond)
+
+		// Still accepting requests during the delay, failing the readiness check
+		res, err := http.Get(url + "/health")
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+
+		close(release)
+
+		assert.NoError(t, <-done)
+		assert.Equal(t, http.StatusNoContent, <-slow)
+		assert.True(t, closed)
+
+		_, err = http.Get(url + "/health")
+		assert.Error(t, err)
+	})
+
+	t.Run("should fail if in-flight requests outlive the timeout", func(t *testing.T) {
+		closed := false
+		a, started, release := newApp(config.Config{
+			ShutdownTimeout: 50 * time.Millisecond,
+		}, func() error {
+			closed = true
+			return nil
+		})
+		defer close(release)
+		url, cancel, done := run(a)
+
+		go func() {
+			if res, err := http.Get(url + "/slow"); err == nil {
+				res.Body.Close()
+			}
+		}()
+		<-started
+
+		cancel()
+
+		err := <-done
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.True(t, closed)
+	})
+
+	t.Run("should drop clients too slow to send the request", func(t *testing.T) {
+		a, _, _ := newApp(config.Config{
+			ShutdownTimeout:   time.Second,
+			ReadHeaderTimeout: 50 * time.Millisecond,
+			ReadTimeout:       100 * time.Millisecond,
+		}, nil)
+		url, cancel, done := run(a)
+		defer func() {
+			cancel()
+			assert.NoError(t, <-done)
+		}()
+		address := strings.TrimPrefix(url, "http://")
+
+		requests := map[string]string{
+			"headers": "GET /health HTTP/1.1\r\nHost: api\r\n",
+			"body":    "POST /health HTTP/1.1\r\nHost: api\r\nContent-Length: 64\r\n\r\n{",
+		}
+		for name, request := range requests {
+			conn, err := net.Dial("tcp", address)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = conn.Write([]byte(request))
+			require.NoError(t, err)
+
+			// Closed by the server long before this deadline
+			require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+			start := time.Now()
+			_, err = io.ReadAll(conn)
+			assert.NoError(t, err, name)
+			assert.Less(t, time.Since(start), time.Second, name)
+		}
+	})
+
+	t.Run("should report a failure to close the database", func(t *testing.T) {
+		a, _, _ := newApp(config.Config{
+			ShutdownTimeout: time.Second,
+		}, func() error {
+			return errors.New("connection reset")
+		})
+		_, cancel, done := run(a)
+
+		cancel()
+
+		assert.EqualError(t, <-done, "connection reset")
+	})
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/rs/zerolog"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
+)
+
+// Body of `POST /users:batch` and `POST /posts:batch`. The items are decoded
+// and validated one by one, so each gets its own outcome.
+type batchRequest struct {
+	// At most `config.Config.MaxBatchItems`, see `createBatch`
+	Items []json.RawMessage `json:"items" binding:"required,min=1"`
+	// `atomic`, the default, creates every item or none, `partial` creates
+	// the valid items even when others fail
+	Mode string `json:"mode" binding:"omitempty,oneof=atomic partial"`
+}
+
+type batchResponse struct {
+	Items []batchResult `json:"items"`
+}
+
+// Outcome of an item of a batch, `status` is the one creating the item alone
+// would have answered with. Holds the created item or the problem.
+type batchResult struct {
+	Status int      `json:"status"`
+	Data   any      `json:"data,omitempty"`
+	Error  *problem `json:"error,omitempty"`
+}
+
+// Status and detail of an item the repository failed to create, or of the
+// whole batch when the failure can't be tied to an item
+type batchItemError func(err error) (status int, detail string)
+
+// Creates the items of a batch with `create`, answering with the outcome of
+// each of them, in order:
+//   - `201 Created` when every item was created.
+//   - `207 Multi-Status` when some failed in partial mode.
+//   - In atomic mode, a problem with the status of the first item that failed,
+//     the items that would have been created are `424 Failed Dependency`.
+//
+// Batches of more than `maxItems` are rejected as a whole.
+func createBatch[T any](ctx *gin.Context, log *zerolog.Logger, maxItems int, create func(context.Context, []T, models.BulkOptions) ([]*T, error), itemError batchItemError) {
+	var req batchRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		log.Info().
+			Err(err).
+			Msg("error validating batch")
+
+		respondValidationProblem(ctx, err)
+		return
+	}
+	if len(req.Items) > maxItems {
+		log.Info().
+			Int("items", len(req.Items)).
+			Msg("batch too large")
+
+		renderProblem(ctx, problem{
+			Status: http.StatusUnprocessableEntity,
+			Detail: "invalid request body",
+			Errors: []fieldError{{Field: "items", Rule: "max", Param: strconv.Itoa(maxItems)}},
+		})
+		return
+	}
+	opts := models.BulkOptions{Partial: req.Mode == "partial"}
+
+	results := make([]batchResult, len(req.Items))
+	valid := make([]T, 0, len(req.Items))
+	indexes := make([]int, 0, len(req.Items))
+	failed := 0
+	for i, raw := range req.Items {
+		var item T
+		if err := decodeBatchItem(raw, &item); err != nil {
+			results[i] = batchResult{Status: http.StatusUnprocessableEntity, Error: itemProblem(validationProblem(err, "item"))}
+			failed++
+			continue
+		}
+		valid = append(valid, item)
+		indexes = append(indexes, i)
+	}
+
+	if len(valid) > 0 && (failed == 0 || opts.Partial) {
+		created, err := create(ctx.Request.Context(), valid, opts)
+
+		var bulkErr *database.BulkError
+		if err != nil && !errors.As(err, &bulkErr) {
+			// Not tied to an item, e.g. a conflict with a request that
+			// raced the checks of the repository
+			status, detail := itemError(err)
+			event := log.Info()
+			if status >= http.StatusInternalServerError {
+				event = log.Error()
+			}
+			event.
+				Err(err).
+				Msg("error inserting batch in database")
+
+			respondProblem(ctx, status, detail)
+			return
+		}
+		if bulkErr != nil {
+			for j, err := range bulkErr.Errors {
+				status, detail := itemError(err)
+				results[indexes[j]] = batchResult{Status: status, Error: itemProblem(problem{Status: status, Detail: detail})}
+				failed++
+			}
+		}
+		for j, item := range created {
+			if item != nil {
+				results[indexes[j]] = batchResult{Status: http.StatusCreated, Data: item}
+			}
+		}
+	}
+
+	log.Info().
+		Int("items", len(req.Items)).
+		Int("failed", failed).
+		Bool("partial", opts.Partial).
+		Msg("batch processed")
+
+	switch {
+	case failed == 0:
+		ctx.JSON(http.StatusCreated, batchResponse{Items: results})
+	case opts.Partial:
+		ctx.JSON(http.StatusMultiStatus, batchResponse{Items: results})
+	default:
+		status := 0
+		for i := range results {
+			if results[i].Status == 0 {
+				results[i] = batchResult{
+					Status: http.StatusFailedDependency,
+					Error:  itemProblem(problem{Status: http.StatusFailedDependency, Detail: "another item of the batch failed"}),
+				}
+			} else if status == 0 {
+				status = results[i].Status
+			}
+		}
+
+		renderProblem(ctx, problem{
+			Status:     status,
+			Detail:     "no item of the batch was created",
+			Extensions: map[string]any{"items": results},
+		})
+	}
+}
+
+// Binds an item of a batch the way gin binds a request body
+func decodeBatchItem(raw json.RawMessage, item any) error {
+	if err := json.Unmarshal(raw, item); err != nil {
+		return err
+	}
+
+	return binding.Validator.ValidateStruct(item)
+}
+
+// Problem of a single item, it has no request of its own
+func itemProblem(p problem) *problem {
+	p.Type = "about:blank"
+	p.Title = http.StatusText(p.Status)
+
+	return &p
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Holds back the response so its validators can be checked before sending it
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// Headers are sent once we know whether the response is a 304
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+// Answers `GET` requests with `304 Not Modified` when the client already has
+// the response, per `If-None-Match` or `If-Modified-Since`.
+//
+// Handlers may set `ETag` and `Last-Modified` themselves, otherwise the ETag
+// is a hash of the body, which is what listings rely on.
+func conditionalGET() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method != http.MethodGet {
+			ctx.Next()
+			return
+		}
+
+		original := ctx.Writer
+		writer := &bufferedWriter{
+			ResponseWriter: original,
+			body:           new(bytes.Buffer),
+		}
+		ctx.Writer = writer
+		// A panic must still reach the client through `gin.Recovery`
+		defer func() {
+			ctx.Writer = original
+		}()
+
+		ctx.Next()
+
+		header := original.Header()
+		if original.Status() == http.StatusOK {
+			if header.Get("ETag") == "" {
+				sum := sha256.Sum256(writer.body.Bytes())
+				header.Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+			}
+			if header.Get("Cache-Control") == "" {
+				header.Set("Cache-Control", "private, no-cache")
+			}
+
+			if notModified(ctx.Request, header) {
+				header.Del("Content-Type")
+				original.WriteHeader(http.StatusNotModified)
+				original.WriteHeaderNow()
+				return
+			}
+		}
+
+		original.WriteHeaderNow()
+		original.Write(writer.body.Bytes())
+	}
+}
+
+// `If-None-Match` takes precedence, `If-Modified-Since` is only looked at when
+// it is absent
+func notModified(req *http.Request, header http.Header) bool {
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, header.Get("ETag"))
+	}
+
+	ims, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.After(ims)
+}
+
+// Weak comparison of a comma separated `If-None-Match` against an ETag
+func etagMatches(list string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_conditionalGET(t *testing.T) {
+	updatedAt := time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(conditionalGET())
+	router.GET("/resource", func(ctx *gin.Context) {
+		setValidators(ctx, updatedAt)
+		ctx.JSON(http.StatusOK, gin.H{"id": 1})
+	})
+	router.GET("/listing", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"data": []int{1, 2}})
+	})
+	router.GET("/missing", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	})
+	router.GET("/panic", func(ctx *gin.Context) {
+		panic("You've met a terrible fate, haven't you?")
+	})
+
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		return w
+	}
+
+	t.Run("should send the validators set by the handler", func(t *testing.T) {
+		w := get("/resource", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `{"id":1}`, w.Body.String())
+		assert.Equal(t, formatETag(updatedAt), w.Header().Get("ETag"))
+		assert.Equal(t, "Wed, 01 Jan 2025 12:00:00 GMT", w.Header().Get("Last-Modified"))
+		assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("should hash the body when the handler sets no ETag", func(t *testing.T) {
+		first := get("/listing", nil)
+		second := get("/listing", nil)
+
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.NotEmpty(t, first.Header().Get("ETag"))
+		assert.Equal(t, first.Header().Get("ETag"), second.Header().Get("ETag"))
+	})
+
+	tests := []struct {
+		Name       string
+		Path       string
+		Headers    map[string]string
+		StatusCode int
+	}{
+		{"should return 304 when If-None-Match matches", "/resource", map[string]string{"If-None-Match": formatETag(updatedAt)}, http.StatusNotModified},
+		{"should return 304 when any If-None-Match matches", "/resource", map[string]string{"If-None-Match": `"1", W/` + formatETag(updatedAt)}, http.StatusNotModified},
+		{"should return 304 when If-None-Match is *", "/listing", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"should return 200 when If-None-Match doesn't match", "/resource", map[string]string{"If-None-Match": `"1"`}, http.StatusOK},
+		{"should return 304 when not modified since", "/resource", map[string]string{"If-Modified-Since": "Wed, 01 Jan 2025 12:00:00 GMT"}, http.StatusNotModified},
+		{"should return 200 when modified since", "/resource", map[string]string{"If-Modified-Since": "Wed, 01 Jan 2025 11:59:59 GMT"}, http.StatusOK},
+		{"should ignore If-Modified-Since when If-None-Match is present", "/resource", map[string]string{"If-None-Match": `"1"`, "If-Modified-Since": "Wed, 01 Jan 2025 12:00:00 GMT"}, http.StatusOK},
+		{"should ignore If-Modified-Since without Last-Modified", "/listing", map[string]string{"If-Modified-Since": "Wed, 01 Jan 2025 12:00:00 GMT"}, http.StatusOK},
+		{"should not touch error responses", "/missing", map[string]string{"If-None-Match": "*"}, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := get(tt.Path, tt.Headers)
+
+			assert.Equal(t, tt.StatusCode, w.Code)
+			if tt.StatusCode == http.StatusNotModified {
+				assert.Empty(t, w.Body.String())
+			} else {
+				assert.NotEmpty(t, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("should return 304 for an unchanged listing", func(t *testing.T) {
+		etag := get("/listing", nil).Header().Get("ETag")
+		w := get("/listing", map[string]string{"If-None-Match": etag})
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Equal(t, etag, w.Header().Get("ETag"))
+	})
+
+	t.Run("should let panics be recovered", func(t *testing.T) {
+		w := get("/panic", nil)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
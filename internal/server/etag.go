@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
+)
+
+var errInvalidIfMatch = errors.New("invalid If-Match header")
+
+// Strong validator of a resource, derived from its last modification time
+func formatETag(updatedAt time.Time) string {
+	return `"` + strconv.FormatInt(updatedAt.UnixMicro(), 10) + `"`
+}
+
+// Lets clients make conditional requests on the resource, see `conditionalGET`
+// and `parseIfMatch`
+func setValidators(ctx *gin.Context, updatedAt time.Time) {
+	ctx.Header("ETag", formatETag(updatedAt))
+	ctx.Header("Last-Modified", updatedAt.UTC().Format(http.TimeFormat))
+}
+
+// Reads `If-Match` as the `updated_at` the client expects the resource to
+// have. Nil when the header is absent or `*`, i.e. there is no condition.
+//
+// The header may list several ETags, the condition holds when any of them
+// matches. `current` reads the `updated_at` of the resource to tell which one
+// it is, and is only called for lists. When none does, or the resource can't
+// be read, the first one is returned for the write to fail on.
+//
+// Only strong ETags we issued can match, weak ones are skipped as the strong
+// comparison requires. A header with none is reported as `errInvalidIfMatch`.
+func parseIfMatch(ctx *gin.Context, current func() (time.Time, error)) (*time.Time, error) {
+	raw := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if raw == "" || raw == "*" {
+		return nil, nil
+	}
+
+	var candidates []time.Time
+	for _, tag := range splitETags(raw) {
+		if updatedAt, ok := parseETag(tag); ok {
+			candidates = append(candidates, updatedAt)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, errInvalidIfMatch
+	case 1:
+		return &candidates[0], nil
+	}
+
+	if updatedAt, err := current(); err == nil {
+		for _, c := range candidates {
+			if c.Equal(updatedAt) {
+				return &c, nil
+			}
+		}
+	}
+
+	return &candidates[0], nil
+}
+
+// Entity tags of a comma separated list, e.g. `"a", W/"b"`. Commas within
+// the quotes belong to the tag
+func splitETags(raw string) []string {
+	var tags []string
+	quoted := false
+	start := 0
+	for i, r := range raw {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			tags = append(tags, strings.TrimSpace(raw[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(tags, strings.TrimSpace(raw[start:]))
+}
+
+// `updated_at` of a strong ETag made by `formatETag`
+func parseETag(tag string) (time.Time, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return time.Time{}, false
+	}
+
+	micros, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.UnixMicro(micros).UTC(), true
+}
+
+// Current `updated_at` of the user, for `parseIfMatch`
+func (a *Application) userUpdatedAt(ctx context.Context, id uint64) func() (time.Time, error) {
+	return func() (time.Time, error) {
+		u, err := a.Users.UserGetByID(ctx, id, models.UserInclude{})
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		return u.UpdatedAt, nil
+	}
+}
+
+// Current `updated_at` of the post, for `parseIfMatch`
+func (a *Application) postUpdatedAt(ctx context.Context, id uint64) func() (time.Time, error) {
+	return func() (time.Time, error) {
+		p, err := a.Posts.PostGetByID(ctx, id, models.PostInclude{})
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		return p.UpdatedAt, nil
+	}
+}
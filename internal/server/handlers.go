@@ -1,7 +1,8 @@
 package server
 
 import (
-	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent"
+	"errors"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/logger"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
 	"github.com/gin-gonic/gin"
@@ -9,32 +10,34 @@ import (
 	"strconv"
 )
 
-func (a *Application) HealthCheck(ctx *gin.Context) {
-	log := logger.FromContext(ctx.Request.Context()).
-		With().
-		Str("handler", "HealthCheck").
-		Logger()
+// Answers as long as the process can serve requests, whatever the state of
+// its dependencies, so a database outage doesn't get the API restarted
+func (a *Application) LivenessCheck(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "OK",
+	})
+}
 
-	log.Info().
-		Msg("pinging database")
+// Fails while a dependency is failing or the API is shutting down, so no
+// requests are routed to it
+func (a *Application) ReadinessCheck(ctx *gin.Context) {
+	a.respondReadiness(ctx, "ReadinessCheck", false)
+}
 
-	if err := a.DB.Ping(ctx.Request.Context()); err != nil {
-		log.Error().
+// Same as `ReadinessCheck`, listing every dependency check with `?verbose=1`
+func (a *Application) HealthCheck(ctx *gin.Context) {
+	verbose, err := strconv.ParseBool(ctx.DefaultQuery("verbose", "false"))
+	if err != nil {
+		logger.FromContext(ctx.Request.Context()).Info().
+			Str("handler", "HealthCheck").
 			Err(err).
-			Msg("failed to ping database")
+			Msg("invalid verbose")
 
-		ctx.JSON(http.StatusServiceUnavailable, gin.H{
-			"status": "service unavailable",
-		})
+		respondProblem(ctx, http.StatusBadRequest, "verbose must be a boolean")
 		return
 	}
 
-	log.Info().
-		Msg("Health check OK")
-
-	ctx.JSON(http.StatusOK, gin.H{
-		"status": "OK",
-	})
+	a.respondReadiness(ctx, "HealthCheck", verbose)
 }
 
 // USERS
@@ -53,20 +56,18 @@ func (a *Application) UserCreate(ctx *gin.Context) {
 			Err(err).
 			Msg("error validating new user")
 
-		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
-			"error": "bad entity",
-		})
+		respondValidationProblem(ctx, err)
 		return
 	}
 
-	dbUser, err := a.DB.UserCreate(reqContext, user)
+	dbUser, err := a.Users.UserCreate(reqContext, user)
 	if err != nil {
-		if ent.IsConstraintError(err) {
+		if errors.Is(err, database.ErrConflict) {
 			log.Info().
 				Interface("user", user).
 				Msg("user already exists")
 
-			ctx.JSON(http.StatusConflict, gin.H{"error": "user already exists"})
+			respondProblem(ctx, http.StatusConflict, "user already exists")
 			return
 		}
 
@@ -74,17 +75,32 @@ func (a *Application) UserCreate(ctx *gin.Context) {
 			Err(err).
 			Msg("error inserting user in database")
 
-		ctx.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "service unavailable",
-		})
+		respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
 		return
 	}
 
 	user.ID = dbUser.ID
+	user.CreatedAt = dbUser.CreatedAt
+	user.UpdatedAt = dbUser.UpdatedAt
 
 	ctx.JSON(http.StatusCreated, user)
 }
 
+func (a *Application) UserCreateBatch(ctx *gin.Context) {
+	log := logger.FromContext(ctx.Request.Context()).
+		With().
+		Str("handler", "UserCreateBatch").
+		Logger()
+
+	createBatch(ctx, &log, a.Config.MaxBatchItems, a.Users.UserCreateBulk, func(err error) (int, string) {
+		if errors.Is(err, database.ErrConflict) {
+			return http.StatusConflict, "user already exists"
+		}
+
+		return http.StatusServiceUnavailable, "service unavailable"
+	})
+}
+
 func (a *Application) UserGetAll(ctx *gin.Context) {
 	reqContext := ctx.Request.Context()
 	log := logger.FromContext(reqContext).
@@ -92,31 +108,45 @@ func (a *Application) UserGetAll(ctx *gin.Context) {
 		Str("handler", "UserGetAll").
 		Logger()
 
-	dbUsers, err := a.DB.UserGetAll(reqContext)
+	query, err := parseUserQuery(ctx)
+	if err != nil {
+		log.Info().
+			Err(err).
+			Msg("invalid query")
+
+		respondProblem(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dbUsers, err := a.Users.UserGetAll(reqContext, query)
 
 	if err != nil {
 		log.Error().
 			Err(err).
 			Msg("error querying database")
 
-		ctx.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "service unavailable",
-		})
+		respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
 		return
 	}
 
-	result := make([]models.User, 0, len(dbUsers))
-	for _, dbU := range dbUsers {
+	result := make([]models.User, 0, len(dbUsers.Items))
+	for _, dbU := range dbUsers.Items {
 		user := models.User{
-			ID:    dbU.ID,
-			Name:  dbU.Name,
-			Email: dbU.Email,
+			ID:        dbU.ID,
+			Name:      dbU.Name,
+			Email:     dbU.Email,
+			CreatedAt: dbU.CreatedAt,
+			UpdatedAt: dbU.UpdatedAt,
+			Posts:     dbU.Posts,
 		}
 
 		result = append(result, user)
 	}
 
-	ctx.JSON(http.StatusOK, result)
+	ctx.JSON(http.StatusOK, listResponse[models.User]{
+		Data:       result,
+		NextCursor: encodeCursor(dbUsers.Next),
+	})
 }
 
 func (a *Application) UserGetByID(ctx *gin.Context) {
@@ -135,19 +165,29 @@ func (a *Application) UserGetByID(ctx *gin.Context) {
 			Str("id", idRaw).
 			Msg("invalid id")
 
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondProblem(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	include, err := parseUserInclude(ctx)
+	if err != nil {
+		log.Info().
+			Err(err).
+			Msg("invalid query")
+
+		respondProblem(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	dbUser, err := a.DB.UserGetByID(reqContext, id)
+	dbUser, err := a.Users.UserGetByID(reqContext, id, include)
 
 	if err != nil {
-		if ent.IsNotFound(err) {
+		if errors.Is(err, database.ErrNotFound) {
 			log.Info().
 				Uint64("id", id).
 				Msg("user not found")
 
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			respondProblem(ctx, http.StatusNotFound, "user not found")
 			return
 		}
 
@@ -155,18 +195,24 @@ func (a *Application) UserGetByID(ctx *gin.Context) {
 			Err(err).
 			Msg("error querying database")
 
-		ctx.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "service unavailable",
-		})
+		respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
 		return
 	}
 
 	user := models.User{
-		ID:    dbUser.ID,
-		Name:  dbUser.Name,
-		Email: dbUser.Email,
+		ID:        dbUser.ID,
+		Name:      dbUser.Name,
+		Email:     dbUser.Email,
+		CreatedAt: dbUser.CreatedAt,
+		UpdatedAt: dbUser.UpdatedAt,
+		Posts:     dbUser.Posts,
 	}
 
+	// Embedded posts change without touching the user, `conditionalGET`
+	// falls back to hashing the body then
+	if !include.Posts {
+		setValidators(ctx, dbUser.UpdatedAt)
+	}
 	ctx.JSON(http.StatusOK, user)
 }
 
@@ -186,19 +232,64 @@ func (a *Application) UserDeleteByID(ctx *gin.Context) {
 			Str("id", idRaw).
 			Msg("invalid id")
 
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondProblem(ctx, http.StatusBadRequest, "invalid id")
 		return
 	}
 
-	err = a.DB.UserDeleteByID(reqContext, id)
+	ifUpdatedAt, err := parseIfMatch(ctx, a.userUpdatedAt(reqContext, id))
+	if err != nil {
+		log.Info().
+			Str("if_match", ctx.GetHeader("If-Match")).
+			Msg("precondition failed")
 
+		respondProblem(ctx, http.StatusPreconditionFailed, "precondition failed")
+		return
+	}
+
+	cascade, err := parseCascade(ctx)
 	if err != nil {
-		if ent.IsNotFound(err) {
+		log.Info().
+			Err(err).
+			Msg("invalid query")
+
+		respondProblem(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = a.Users.UserDeleteByID(reqContext, id, models.DeleteOptions{
+		IfUpdatedAt: ifUpdatedAt,
+		Cascade:     cascade,
+	})
+
+	if err != nil {
+		if errors.Is(err, database.ErrPreconditionFailed) {
+			log.Info().
+				Uint64("id", id).
+				Msg("precondition failed")
+
+			respondProblem(ctx, http.StatusPreconditionFailed, "precondition failed")
+			return
+		}
+		if errors.Is(err, database.ErrNotFound) {
 			log.Info().
 				Uint64("id", id).
 				Msg("user not found")
 
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			respondProblem(ctx, http.StatusNotFound, "user not found")
+			return
+		}
+		var hasPosts *database.UserHasPostsError
+		if errors.As(err, &hasPosts) {
+			log.Info().
+				Uint64("id", id).
+				Int("posts", hasPosts.Posts).
+				Msg("user has posts")
+
+			renderProblem(ctx, problem{
+				Status:     http.StatusConflict,
+				Detail:     "user has posts",
+				Extensions: map[string]any{"posts": hasPosts.Posts},
+			})
 			return
 		}
 
@@ -207,9 +298,7 @@ func (a *Application) UserDeleteByID(ctx *gin.Context) {
 			Err(err).
 			Msg("error deleting user in database")
 
-		ctx.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "service unavailable",
-		})
+		respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
 		return
 	}
 
@@ -230,9 +319,7 @@ func (a *Application) UserUpdateByID(ctx *gin.Context) {
 			Err(err).
 			Msg("error validating new user")
 
-		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
-			"error": "bad entity",
-		})
+		respondValidationProblem(ctx, err)
 		return
 	}
 
@@ -243,30 +330,49 @@ func (a *Application) UserUpdateByID(ctx *gin.Context) {
 			Str("id", idRaw).
 			Msg("invalid id")
 
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondProblem(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	ifUpdatedAt, err := parseIfMatch(ctx, a.userUpdatedAt(reqContext, id))
+	if err != nil {
+		log.Info().
+			Str("if_match", ctx.GetHeader("If-Match")).
+			Msg("precondition failed")
+
+		respondProblem(ctx, http.StatusPreconditionFailed, "precondition failed")
 		return
 	}
 
-	updatedUser, err := a.DB.UserUpdate(reqContext, models.UserUpdate{
-		ID:    &id,
-		Name:  user.Name,
-		Email: user.Email,
+	updatedUser, err := a.Users.UserUpdate(reqContext, models.UserUpdate{
+		ID:          &id,
+		Name:        user.Name,
+		Email:       user.Email,
+		IfUpdatedAt: ifUpdatedAt,
 	})
 	if err != nil {
-		if ent.IsNotFound(err) {
+		if errors.Is(err, database.ErrPreconditionFailed) {
+			log.Info().
+				Uint64("id", id).
+				Msg("precondition failed")
+
+			respondProblem(ctx, http.StatusPreconditionFailed, "precondition failed")
+			return
+		}
+		if errors.Is(err, database.ErrNotFound) {
 			log.Info().
 				Uint64("id", id).
 				Msg("user not found")
 
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			respondProblem(ctx, http.StatusNotFound, "user not found")
 			return
 		}
-		if ent.IsConstraintError(err) {
+		if errors.Is(err, database.ErrConflict) {
 			log.Info().
 				Interface("user", user).
 				Msg("email already exists")
 
-			ctx.JSON(http.StatusConflict, gin.H{"error": "email already in use"})
+			respondProblem(ctx, http.StatusConflict, "email already in use")
 			return
 		}
 
@@ -274,15 +380,270 @@ func (a *Application) UserUpdateByID(ctx *gin.Context) {
 			Err(err).
 			Msg("error updating user in database")
 
-		ctx.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "service unavailable",
-		})
+		respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
 		return
 	}
 
+	setValidators(ctx, updatedUser.UpdatedAt)
 	ctx.JSON(http.StatusOK, updatedUser)
 }
 
+func (a *Application) UserPatchByID(ctx *gin.Context) {
+	reqContext := ctx.Request.Context()
+	log := logger.FromContext(reqContext).
+		With().
+		Str("handler", "UserPatchByID").
+		Logger()
+
+	var patch models.UserPatch
+	err := bindMergePatch(ctx, &patch)
+	if err != nil {
+		log.Info().
+			Err(err).
+			Msg("error validating user patch")
+
+		if errors.Is(err, errUnsupportedMediaType) {
+			respondProblem(ctx, http.StatusUnsupportedMediaType, "unsupported media type")
+			return
+		}
+
+		respondValidationProblem(ctx, err)
+		return
+	}
+
+	idRaw := ctx.Param("id")
+	id, err := strconv.ParseUint(idRaw, 10, 64)
+	if err != nil {
+		log.Info().
+			Str("id", idRaw).
+			Msg("invalid id")
+
+		respondProblem(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	ifUpdatedAt, err := parseIfMatch(ctx, a.userUpdatedAt(reqContext, id))
+	if err != nil {
+		log.Info().
+			Str("if_match", ctx.GetHeader("If-Match")).
+			Msg("precondition failed")
+
+		respondProblem(ctx, http.StatusPreconditionFailed, "precondition failed")
+		return
+	}
+
+	patch.ID = &id
+	patch.IfUpdatedAt = ifUpdatedAt
+	patchedUser, err := a.Users.UserPatch(reqContext, patch)
+	if err != nil {
+		if errors.Is(err, database.ErrPreconditionFailed) {
+			log.Info().
+				Uint64("id", id).
+				Msg("precondition failed")
+
+			respondProblem(ctx, http.StatusPreconditionFailed, "precondition failed")
+			return
+		}
+		if errors.Is(err, database.ErrNotFound) {
+			log.Info().
+				Uint64("id", id).
+				Msg("user not found")
+
+			respondProblem(ctx, http.StatusNotFound, "user not found")
+			return
+		}
+		if errors.Is(err, database.ErrConflict) {
+			log.Info().
+				Interface("patch", patch).
+				Msg("email already exists")
+
+			respondProblem(ctx, http.StatusConflict, "email already in use")
+			return
+		}
+
+		log.Error().
+			Err(err).
+			Msg("error patching user in database")
+
+		respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
+		return
+	}
+
+	setValidators(ctx, patchedUser.UpdatedAt)
+	ctx.JSON(http.StatusOK, patchedUser)
+}
+
+func (a *Application) UserRestoreByID(ctx *gin.Context) {
+	reqContext := ctx.Request.Context()
+	log := logger.FromContext(reqContext).
+		With().
+		Str("handler", "UserRestoreByID").
+		Logger()
+
+	idRaw := ctx.Param("id")
+	id, err := strconv.ParseUint(idRaw, 10, 64)
+	if err != nil {
+		log.Info().
+			Str("id", idRaw).
+			Msg("invalid id")
+
+		respondProblem(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	restoredUser, err := a.Users.UserRestoreByID(reqContext, id)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			log.Info().
+				Uint64("id", id).
+				Msg("user not found")
+
+			respondProblem(ctx, http.StatusNotFound, "user not found")
+			return
+		}
+
+		log.Error().
+			Err(err).
+			Msg("error restoring user in database")
+
+		respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
+		return
+	}
+
+	setValidators(ctx, restoredUser.UpdatedAt)
+	ctx.JSON(http.StatusOK, restoredUser)
+}
+
+func (a *Application) UserPostGetAll(ctx *gin.Context) {
+	reqContext := ctx.Request.Context()
+	log := logger.FromContext(reqContext).
+		With().
+		Str("handler", "UserPostGetAll").
+		Logger()
+
+	idRaw := ctx.Param("id")
+	id, err := strconv.ParseUint(idRaw, 10, 64)
+	if err != nil {
+		log.Info().
+			Str("id", idRaw).
+			Msg("invalid id")
+
+		respondProblem(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	query, err := parsePostQuery(ctx, userPostQueryParams)
+	if err != nil {
+		log.Info().
+			Err(err).
+			Msg("invalid query")
+
+		respondProblem(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dbPosts, err := a.Users.UserGetPosts(reqContext, id, query)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			log.Info().
+				Uint64("id", id).
+				Msg("user not found")
+
+			respondProblem(ctx, http.StatusNotFound, "user not found")
+			return
+		}
+
+		log.Error().
+			Err(err).
+			Msg("error querying database")
+
+		respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
+		return
+	}
+
+	result := make([]models.Post, 0, len(dbPosts.Items))
+	for _, dbP := range dbPosts.Items {
+		post := models.Post{
+			ID:        dbP.ID,
+			Title:     dbP.Title,
+			Content:   dbP.Content,
+			UserID:    dbP.UserID,
+			CreatedAt: dbP.CreatedAt,
+			UpdatedAt: dbP.UpdatedAt,
+			User:      dbP.User,
+		}
+
+		result = append(result, post)
+	}
+
+	ctx.JSON(http.StatusOK, listResponse[models.Post]{
+		Data:       result,
+		NextCursor: encodeCursor(dbPosts.Next),
+	})
+}
+
+func (a *Application) UserPostCreate(ctx *gin.Context) {
+	reqContext := ctx.Request.Context()
+	log := logger.FromContext(reqContext).
+		With().
+		Str("handler", "UserPostCreate").
+		Logger()
+
+	idRaw := ctx.Param("id")
+	id, err := strconv.ParseUint(idRaw, 10, 64)
+	if err != nil {
+		log.Info().
+			Str("id", idRaw).
+			Msg("invalid id")
+
+		respondProblem(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var userPost models.UserPost
+	err = ctx.ShouldBindBodyWithJSON(&userPost)
+	if err != nil {
+		log.Info().
+			Err(err).
+			Msg("error validating new post")
+
+		respondValidationProblem(ctx, err)
+		return
+	}
+
+	post := models.Post{
+		Title:   userPost.Title,
+		Content: userPost.Content,
+		UserID:  id,
+	}
+
+	dbPost, err := a.Posts.PostCreate(reqContext, post)
+	if err != nil {
+		// The only constraint a new post can break is its user not existing
+		if errors.Is(err, database.ErrForeignKey) || errors.Is(err, database.ErrUserDeleted) {
+			log.Info().
+				Uint64("id", id).
+				Msg("user not found")
+
+			respondProblem(ctx, http.StatusNotFound, "user not found")
+			return
+		}
+
+		log.Error().
+			Err(err).
+			Msg("error inserting post in database")
+
+		respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
+		return
+	}
+
+	post.ID = dbPost.ID
+	post.CreatedAt = dbPost.CreatedAt
+	post.UpdatedAt = dbPost.UpdatedAt
+
+	ctx.JSON(http.StatusCreated, post)
+}
+
 // POSTS
 func (a *Application) PostCreate(ctx *gin.Context) {
 	reqContext := ctx.Request.Context()
@@ -299,20 +660,18 @@ func (a *Application) PostCreate(ctx *gin.Context) {
 			Err(err).
 			Msg("error validating new post")
 
-		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
-			"error": "bad entity",
-		})
+		respondValidationProblem(ctx, err)
 		return
 	}
 
-	dbPost, err := a.DB.PostCreate(reqContext, post)
+	dbPost, err := a.Posts.PostCreate(reqContext, post)
 	if err != nil {
-		if ent.IsConstraintError(err) {
+		if errors.Is(err, database.ErrForeignKey) || errors.Is(err, database.ErrUserDeleted) {
 			log.Info().
 				Interface("post", post).
 				Msg("associated userID not in DB")
 
-			ctx.JSON(http.StatusConflict, gin.H{"error": "userID doesn't exist"})
+			respondProblem(ctx, http.StatusConflict, "userID doesn't exist")
 			return
 		}
 
@@ -320,17 +679,32 @@ func (a *Application) PostCreate(ctx *gin.Context) {
 			Err(err).
 			Msg("error inserting post in database")
 
-		ctx.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "service unavailable",
-		})
+		respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
 		return
 	}
 
 	post.ID = dbPost.ID
+	post.CreatedAt = dbPost.CreatedAt
+	post.UpdatedAt = dbPost.UpdatedAt
 
 	ctx.JSON(http.StatusCreated, post)
 }
 
+func (a *Application) PostCreateBatch(ctx *gin.Context) {
+	log := logger.FromContext(ctx.Request.Context()).
+		With().
+		Str("handler", "PostCreateBatch").
+		Logger()
+
+	createBatch(ctx, &log, a.Config.MaxBatchItems, a.Posts.PostCreateBulk, func(err error) (int, string) {
+		if errors.Is(err, database.ErrForeignKey) || errors.Is(err, database.ErrUserDeleted) {
+			return http.StatusConflict, "userID doesn't exist"
+		}
+
+		return http.StatusServiceUnavailable, "service unavailable"
+	})
+}
+
 func (a *Application) PostGetAll(ctx *gin.Context) {
 	reqContext := ctx.Request.Context()
 	log := logger.FromContext(reqContext).
@@ -338,32 +712,46 @@ func (a *Application) PostGetAll(ctx *gin.Context) {
 		Str("handler", "PostGetAll").
 		Logger()
 
-	dbPosts, err := a.DB.PostGetAll(reqContext)
+	query, err := parsePostQuery(ctx, postQueryParams)
+	if err != nil {
+		log.Info().
+			Err(err).
+			Msg("invalid query")
+
+		respondProblem(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dbPosts, err := a.Posts.PostGetAll(reqContext, query)
 
 	if err != nil {
 		log.Error().
 			Err(err).
 			Msg("error querying database")
 
-		ctx.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "service unavailable",
-		})
+		respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
 		return
 	}
 
-	result := make([]models.Post, 0, len(dbPosts))
-	for _, dbP := range dbPosts {
+	result := make([]models.Post, 0, len(dbPosts.Items))
+	for _, dbP := range dbPosts.Items {
 		post := models.Post{
-			ID:      dbP.ID,
-			Title:   dbP.Title,
-			Content: dbP.Content,
-			UserID:  dbP.UserID,
+			ID:        dbP.ID,
+			Title:     dbP.Title,
+			Content:   dbP.Content,
+			UserID:    dbP.UserID,
+			CreatedAt: dbP.CreatedAt,
+			UpdatedAt: dbP.UpdatedAt,
+			User:      dbP.User,
 		}
 
 		result = append(result, post)
 	}
 
-	ctx.JSON(http.StatusOK, result)
+	ctx.JSON(http.StatusOK, listResponse[models.Post]{
+		Data:       result,
+		NextCursor: encodeCursor(dbPosts.Next),
+	})
 }
 
 func (a *Application) PostGetByID(ctx *gin.Context) {
@@ -381,19 +769,29 @@ func (a *Application) PostGetByID(ctx *gin.Context) {
 			Str("id", idRaw).
 			Msg("invalid id")
 
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondProblem(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	include, err := parsePostInclude(ctx)
+	if err != nil {
+		log.Info().
+			Err(err).
+			Msg("invalid query")
+
+		respondProblem(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	dbPost, err := a.DB.PostGetByID(reqContext, id)
+	dbPost, err := a.Posts.PostGetByID(reqContext, id, include)
 
 	if err != nil {
-		if ent.IsNotFound(err) {
+		if errors.Is(err, database.ErrNotFound) {
 			log.Info().
 				Uint64("id", id).
 				Msg("post not found")
 
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+			respondProblem(ctx, http.StatusNotFound, "post not found")
 			return
 		}
 
@@ -401,19 +799,24 @@ func (a *Application) PostGetByID(ctx *gin.Context) {
 			Err(err).
 			Msg("error querying database")
 
-		ctx.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "service unavailable",
-		})
+		respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
 		return
 	}
 
 	post := models.Post{
-		ID:      dbPost.ID,
-		Title:   dbPost.Title,
-		Content: dbPost.Content,
-		UserID:  dbPost.UserID,
+		ID:        dbPost.ID,
+		Title:     dbPost.Title,
+		Content:   dbPost.Content,
+		UserID:    dbPost.UserID,
+		CreatedAt: dbPost.CreatedAt,
+		UpdatedAt: dbPost.UpdatedAt,
+		User:      dbPost.User,
 	}
 
+	// Same as `UserGetByID`, the embedded user changes on its own
+	if !include.User {
+		setValidators(ctx, dbPost.UpdatedAt)
+	}
 	ctx.JSON(http.StatusOK, post)
 }
 
@@ -432,19 +835,39 @@ func (a *Application) PostDeleteByID(ctx *gin.Context) {
 			Str("id", idRaw).
 			Msg("invalid id")
 
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondProblem(ctx, http.StatusBadRequest, "invalid id")
 		return
 	}
 
-	err = a.DB.PostDeleteByID(reqContext, id)
+	ifUpdatedAt, err := parseIfMatch(ctx, a.postUpdatedAt(reqContext, id))
+	if err != nil {
+		log.Info().
+			Str("if_match", ctx.GetHeader("If-Match")).
+			Msg("precondition failed")
+
+		respondProblem(ctx, http.StatusPreconditionFailed, "precondition failed")
+		return
+	}
+
+	err = a.Posts.PostDeleteByID(reqContext, id, models.DeleteOptions{
+		IfUpdatedAt: ifUpdatedAt,
+	})
 
 	if err != nil {
-		if ent.IsNotFound(err) {
+		if errors.Is(err, database.ErrPreconditionFailed) {
+			log.Info().
+				Uint64("id", id).
+				Msg("precondition failed")
+
+			respondProblem(ctx, http.StatusPreconditionFailed, "precondition failed")
+			return
+		}
+		if errors.Is(err, database.ErrNotFound) {
 			log.Info().
 				Uint64("id", id).
 				Msg("post not found")
 
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+			respondProblem(ctx, http.StatusNotFound, "post not found")
 			return
 		}
 
@@ -452,15 +875,54 @@ func (a *Application) PostDeleteByID(ctx *gin.Context) {
 			Err(err).
 			Msg("error deleting post in database")
 
-		ctx.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "service unavailable",
-		})
+		respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
 		return
 	}
 
 	ctx.Status(http.StatusNoContent)
 }
 
+func (a *Application) PostDeleteAll(ctx *gin.Context) {
+	reqContext := ctx.Request.Context()
+	log := logger.FromContext(reqContext).
+		With().
+		Str("handler", "PostDeleteAll").
+		Logger()
+
+	userID, err := parsePostDeleteQuery(ctx)
+	if err != nil {
+		log.Info().
+			Err(err).
+			Msg("invalid query")
+
+		respondProblem(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	deleted, err := a.Posts.PostDeleteByUserID(reqContext, userID)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			log.Info().
+				Uint64("user_id", userID).
+				Msg("user not found")
+
+			respondProblem(ctx, http.StatusNotFound, "user not found")
+			return
+		}
+
+		log.Error().
+			Err(err).
+			Msg("error deleting posts in database")
+
+		respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"deleted": deleted,
+	})
+}
+
 func (a *Application) PostUpdateByID(ctx *gin.Context) {
 	reqContext := ctx.Request.Context()
 	log := logger.FromContext(reqContext).
@@ -476,9 +938,7 @@ func (a *Application) PostUpdateByID(ctx *gin.Context) {
 			Err(err).
 			Msg("error validating new post")
 
-		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
-			"error": "bad entity",
-		})
+		respondValidationProblem(ctx, err)
 		return
 	}
 
@@ -490,28 +950,47 @@ func (a *Application) PostUpdateByID(ctx *gin.Context) {
 			Str("id", idRaw).
 			Msg("invalid id")
 
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondProblem(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	ifUpdatedAt, err := parseIfMatch(ctx, a.postUpdatedAt(reqContext, id))
+	if err != nil {
+		log.Info().
+			Str("if_match", ctx.GetHeader("If-Match")).
+			Msg("precondition failed")
+
+		respondProblem(ctx, http.StatusPreconditionFailed, "precondition failed")
 		return
 	}
 
 	post.ID = &id
-	updatedPost, err := a.DB.PostUpdate(reqContext, post)
+	post.IfUpdatedAt = ifUpdatedAt
+	updatedPost, err := a.Posts.PostUpdate(reqContext, post)
 	if err != nil {
-		if ent.IsNotFound(err) {
+		if errors.Is(err, database.ErrPreconditionFailed) {
+			log.Info().
+				Uint64("id", id).
+				Msg("precondition failed")
+
+			respondProblem(ctx, http.StatusPreconditionFailed, "precondition failed")
+			return
+		}
+		if errors.Is(err, database.ErrNotFound) {
 			log.Info().
 				Uint64("id", id).
 				Msg("post not found")
 
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+			respondProblem(ctx, http.StatusNotFound, "post not found")
 			return
 		}
 
-		if ent.IsConstraintError(err) {
+		if errors.Is(err, database.ErrForeignKey) {
 			log.Info().
 				Interface("post", post).
 				Msg("associated userID not in DB")
 
-			ctx.JSON(http.StatusConflict, gin.H{"error": "userID doesn't exist"})
+			respondProblem(ctx, http.StatusConflict, "userID doesn't exist")
 			return
 		}
 
@@ -519,11 +998,136 @@ func (a *Application) PostUpdateByID(ctx *gin.Context) {
 			Err(err).
 			Msg("error updating post in database")
 
-		ctx.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "service unavailable",
-		})
+		respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
 		return
 	}
 
+	setValidators(ctx, updatedPost.UpdatedAt)
 	ctx.JSON(http.StatusOK, updatedPost)
 }
+
+func (a *Application) PostPatchByID(ctx *gin.Context) {
+	reqContext := ctx.Request.Context()
+	log := logger.FromContext(reqContext).
+		With().
+		Str("handler", "PostPatchByID").
+		Logger()
+
+	var patch models.PostPatch
+	err := bindMergePatch(ctx, &patch)
+	if err != nil {
+		log.Info().
+			Err(err).
+			Msg("error validating post patch")
+
+		if errors.Is(err, errUnsupportedMediaType) {
+			respondProblem(ctx, http.StatusUnsupportedMediaType, "unsupported media type")
+			return
+		}
+
+		respondValidationProblem(ctx, err)
+		return
+	}
+
+	idRaw := ctx.Param("id")
+	id, err := strconv.ParseUint(idRaw, 10, 64)
+	if err != nil {
+		log.Info().
+			Str("id", idRaw).
+			Msg("invalid id")
+
+		respondProblem(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	ifUpdatedAt, err := parseIfMatch(ctx, a.postUpdatedAt(reqContext, id))
+	if err != nil {
+		log.Info().
+			Str("if_match", ctx.GetHeader("If-Match")).
+			Msg("precondition failed")
+
+		respondProblem(ctx, http.StatusPreconditionFailed, "precondition failed")
+		return
+	}
+
+	patch.ID = &id
+	patch.IfUpdatedAt = ifUpdatedAt
+	patchedPost, err := a.Posts.PostPatch(reqContext, patch)
+	if err != nil {
+		if errors.Is(err, database.ErrPreconditionFailed) {
+			log.Info().
+				Uint64("id", id).
+				Msg("precondition failed")
+
+			respondProblem(ctx, http.StatusPreconditionFailed, "precondition failed")
+			return
+		}
+		if errors.Is(err, database.ErrNotFound) {
+			log.Info().
+				Uint64("id", id).
+				Msg("post not found")
+
+			respondProblem(ctx, http.StatusNotFound, "post not found")
+			return
+		}
+
+		log.Error().
+			Err(err).
+			Msg("error patching post in database")
+
+		respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
+		return
+	}
+
+	setValidators(ctx, patchedPost.UpdatedAt)
+	ctx.JSON(http.StatusOK, patchedPost)
+}
+
+func (a *Application) PostRestoreByID(ctx *gin.Context) {
+	reqContext := ctx.Request.Context()
+	log := logger.FromContext(reqContext).
+		With().
+		Str("handler", "PostRestoreByID").
+		Logger()
+
+	idRaw := ctx.Param("id")
+	id, err := strconv.ParseUint(idRaw, 10, 64)
+	if err != nil {
+		log.Info().
+			Str("id", idRaw).
+			Msg("invalid id")
+
+		respondProblem(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	restoredPost, err := a.Posts.PostRestoreByID(reqContext, id)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			log.Info().
+				Uint64("id", id).
+				Msg("post not found")
+
+			respondProblem(ctx, http.StatusNotFound, "post not found")
+			return
+		}
+		if errors.Is(err, database.ErrUserDeleted) {
+			log.Info().
+				Uint64("id", id).
+				Msg("post user is deleted")
+
+			respondProblem(ctx, http.StatusConflict, "user is deleted")
+			return
+		}
+
+		log.Error().
+			Err(err).
+			Msg("error restoring post in database")
+
+		respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
+		return
+	}
+
+	setValidators(ctx, restoredPost.UpdatedAt)
+	ctx.JSON(http.StatusOK, restoredPost)
+}
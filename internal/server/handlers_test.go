@@ -7,16 +7,18 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gkampitakis/go-snaps/snaps"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/inmemory"
-	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/ent"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
 )
 
 func Test_Application_Health(t *testing.T) {
+	resetDB(t)
 	app.Router.GET("/health", app.HealthCheck)
 
 	t.Run("should return 200 if PING to DB is ok", func(t *testing.T) {
@@ -44,10 +46,23 @@ func Test_Application_Health(t *testing.T) {
 		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 		snaps.MatchJSON(t, w.Body.String())
 	})
+
+	t.Run("should return 503 while shutting down", func(t *testing.T) {
+		app.shuttingDown.Store(true)
+		defer app.shuttingDown.Store(false)
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/health", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
 }
 
 // USERS
 func Test_Application_UserCreate(t *testing.T) {
+	resetDB(t)
 	app.Router.POST("/users", app.UserCreate)
 
 	tests := []struct {
@@ -58,7 +73,7 @@ func Test_Application_UserCreate(t *testing.T) {
 		{
 			"should return 201 if user is created on DB",
 			201,
-			`{"name":"Daniel Levy Moreno","email":"danielmorenolevy@gmail.com"}`,
+			`{"name":"Daniel Levy Moreno","email":"daniel@levy.dev"}`,
 		},
 		{
 			"should return 422 if user is malformed",
@@ -80,15 +95,6 @@ func Test_Application_UserCreate(t *testing.T) {
 	}
 
 	t.Run("should return 409 if email is already is in used", func(t *testing.T) {
-		oldUserCreateFn := inmemory.InMemoryUserCreateFn
-		defer func() {
-			inmemory.InMemoryUserCreateFn = oldUserCreateFn
-		}()
-
-		inmemory.InMemoryUserCreateFn = func(ctx context.Context, u models.User) (*models.User, error) {
-			return nil, &ent.ConstraintError{}
-		}
-
 		reader := strings.NewReader(`{"name":"Daniel Levy Moreno","email":"danielmorenolevy@gmail.com"}`)
 		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/users", reader))
 		w := httptest.NewRecorder()
@@ -118,7 +124,119 @@ func Test_Application_UserCreate(t *testing.T) {
 	})
 }
 
+func Test_Application_UserCreateBatch(t *testing.T) {
+	resetDB(t)
+	customMethod(app.Router, "/users", "batch", app.UserCreateBatch)
+
+	tests := []struct {
+		Name        string
+		StatusCode  int
+		RequestBody string
+	}{
+		{
+			"should return 201 if every user is created on DB",
+			http.StatusCreated,
+			`{"items":[{"name":"Rick Doe","email":"rickdoe@gmail.com"},{"name":"Mary Doe","email":"marydoe@gmail.com"}]}`,
+		},
+		{
+			"should return 409 and create none if a user already exists",
+			http.StatusConflict,
+			`{"items":[{"name":"Tom Doe","email":"tomdoe@gmail.com"},{"name":"John Doe","email":"johnnydoe@gmail.com"}]}`,
+		},
+		{
+			"should return 422 and create none if a user is malformed",
+			http.StatusUnprocessableEntity,
+			`{"items":[{"name":"Tom Doe","email":"tomdoe@gmail.com"},{"name":"Tom Doe","email":"not an email"},42]}`,
+		},
+		{
+			"should return 207 with the outcome of each user in partial mode",
+			http.StatusMultiStatus,
+			`{"mode":"partial","items":[{"name":"Tom Doe","email":"tomdoe@gmail.com"},{"name":"John Doe","email":"johnnydoe@gmail.com"},{"name":"Tom Doe"}]}`,
+		},
+		{
+			"should return 422 if the batch is empty",
+			http.StatusUnprocessableEntity,
+			`{"items":[]}`,
+		},
+		{
+			"should return 422 if the mode is unknown",
+			http.StatusUnprocessableEntity,
+			`{"mode":"some","items":[{"name":"Tom Doe","email":"tomdoe@gmail.com"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			reader := strings.NewReader(tt.RequestBody)
+			req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/users:batch", reader))
+			w := httptest.NewRecorder()
+			app.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.StatusCode, w.Code)
+			snaps.MatchJSON(t, w.Body.String())
+		})
+	}
+
+	t.Run("should return 422 if the batch is too large", func(t *testing.T) {
+		items := strings.Repeat(`{"name":"Tom Doe","email":"tomdoe@gmail.com"},`, 1001)
+		reader := strings.NewReader(`{"items":[` + strings.TrimSuffix(items, ",") + `]}`)
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/users:batch", reader))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 503 if unknown error occurs", func(t *testing.T) {
+		oldUserCreateBulkFn := inmemory.InMemoryUserCreateBulkFn
+		defer func() {
+			inmemory.InMemoryUserCreateBulkFn = oldUserCreateBulkFn
+		}()
+		inmemory.InMemoryUserCreateBulkFn = func(ctx context.Context, users []models.User, opts models.BulkOptions) ([]*models.User, error) {
+			return nil, errors.New("something terrible happened")
+		}
+
+		reader := strings.NewReader(`{"items":[{"name":"Tom Doe","email":"tomdoe@gmail.com"}]}`)
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/users:batch", reader))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 409 if a user is created by another request meanwhile", func(t *testing.T) {
+		oldUserCreateBulkFn := inmemory.InMemoryUserCreateBulkFn
+		defer func() {
+			inmemory.InMemoryUserCreateBulkFn = oldUserCreateBulkFn
+		}()
+		inmemory.InMemoryUserCreateBulkFn = func(ctx context.Context, users []models.User, opts models.BulkOptions) ([]*models.User, error) {
+			return nil, &database.ConflictError{Field: "email"}
+		}
+
+		reader := strings.NewReader(`{"items":[{"name":"Tom Doe","email":"tomdoe@gmail.com"}]}`)
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/users:batch", reader))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 404 for other custom methods", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/users:purge", strings.NewReader(`{}`)))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+		snaps.MatchJSON(t, w.Body.String())
+	})
+}
+
 func Test_Application_UserGetAll(t *testing.T) {
+	resetDB(t)
 	app.Router.GET("/users", app.UserGetAll)
 
 	t.Run("should return 200 with all data", func(t *testing.T) {
@@ -130,13 +248,56 @@ func Test_Application_UserGetAll(t *testing.T) {
 		snaps.MatchJSON(t, w.Body.String())
 	})
 
+	pageTests := []struct {
+		Name       string
+		Query      string
+		StatusCode int
+	}{
+		{"should return the first page", "?limit=1", http.StatusOK},
+		{"should return a middle page", "?limit=1&after=" + *encodeCursor(&models.Cursor{ID: 1}), http.StatusOK},
+		{"should return the last page without next cursor", "?limit=1&after=" + *encodeCursor(&models.Cursor{ID: 2}), http.StatusOK},
+		{"should return 400 when limit is out of range", "?limit=0", http.StatusBadRequest},
+		{"should return 400 when limit is not a number", "?limit=ten", http.StatusBadRequest},
+		{"should return 400 when cursor is malformed", "?after=not-a-cursor", http.StatusBadRequest},
+		{"should return 400 when parameter is unknown", "?foo=1", http.StatusBadRequest},
+	}
+
+	for _, tt := range pageTests {
+		t.Run(tt.Name, func(t *testing.T) {
+			req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/users"+tt.Query, nil))
+			w := httptest.NewRecorder()
+			app.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.StatusCode, w.Code)
+			snaps.MatchJSON(t, w.Body.String())
+		})
+	}
+
+	t.Run("should embed posts with include=posts", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/users?include=posts", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 400 when include is unknown", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/users?include=comments", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
 	t.Run("should return 200 with all data when empty", func(t *testing.T) {
 		oldUserGetAllFunc := inmemory.InMemoryUserGetAllFn
 		defer func() {
 			inmemory.InMemoryUserGetAllFn = oldUserGetAllFunc
 		}()
-		inmemory.InMemoryUserGetAllFn = func(ctx context.Context) ([]*models.User, error) {
-			return []*models.User{}, nil
+		inmemory.InMemoryUserGetAllFn = func(ctx context.Context, query models.UserQuery) (*models.Page[*models.User], error) {
+			return &models.Page[*models.User]{Items: []*models.User{}}, nil
 		}
 
 		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/users", nil))
@@ -152,7 +313,7 @@ func Test_Application_UserGetAll(t *testing.T) {
 		defer func() {
 			inmemory.InMemoryUserGetAllFn = oldUserGetAllFunc
 		}()
-		inmemory.InMemoryUserGetAllFn = func(ctx context.Context) ([]*models.User, error) {
+		inmemory.InMemoryUserGetAllFn = func(ctx context.Context, query models.UserQuery) (*models.Page[*models.User], error) {
 			return nil, errors.New("You've met a terrible fate, haven't you?")
 		}
 
@@ -166,6 +327,7 @@ func Test_Application_UserGetAll(t *testing.T) {
 }
 
 func Test_Application_UserGetByID(t *testing.T) {
+	resetDB(t)
 	app.Router.GET("/users/:id", app.UserGetByID)
 
 	t.Run("should return 200 with user data", func(t *testing.T) {
@@ -177,6 +339,33 @@ func Test_Application_UserGetByID(t *testing.T) {
 		snaps.MatchJSON(t, w.Body.String())
 	})
 
+	t.Run("should embed posts with include=posts", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/users/1?include=posts", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should embed an empty list when user has no posts", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/users/3?include=posts", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 400 when include is unknown", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/users/1?include=user", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
 	t.Run("should return 400 when id is malformed", func(t *testing.T) {
 		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/users/hahaha", nil))
 		w := httptest.NewRecorder()
@@ -191,8 +380,8 @@ func Test_Application_UserGetByID(t *testing.T) {
 		defer func() {
 			inmemory.InMemoryUserGetByIDFn = oldUserGetByIDFunc
 		}()
-		inmemory.InMemoryUserGetByIDFn = func(ctx context.Context, id uint64) (*models.User, error) {
-			return nil, &ent.NotFoundError{}
+		inmemory.InMemoryUserGetByIDFn = func(ctx context.Context, id uint64, include models.UserInclude) (*models.User, error) {
+			return nil, database.ErrNotFound
 		}
 
 		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/users/1", nil))
@@ -208,7 +397,7 @@ func Test_Application_UserGetByID(t *testing.T) {
 		defer func() {
 			inmemory.InMemoryUserGetByIDFn = oldUserGetByIDFunc
 		}()
-		inmemory.InMemoryUserGetByIDFn = func(ctx context.Context, id uint64) (*models.User, error) {
+		inmemory.InMemoryUserGetByIDFn = func(ctx context.Context, id uint64, include models.UserInclude) (*models.User, error) {
 			return nil, errors.New("You've met a terrible fate, haven't you?")
 		}
 
@@ -219,13 +408,34 @@ func Test_Application_UserGetByID(t *testing.T) {
 		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 		snaps.MatchJSON(t, w.Body.String())
 	})
+
+	t.Run("should return an ETag derived from updated_at", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/users/1", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"1735732800000000"`, w.Header().Get("ETag"))
+		assert.Equal(t, "Wed, 01 Jan 2025 12:00:00 GMT", w.Header().Get("Last-Modified"))
+	})
+
+	t.Run("should leave validators to conditionalGET with include=posts", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/users/1?include=posts", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("ETag"))
+		assert.Empty(t, w.Header().Get("Last-Modified"))
+	})
 }
 
 func Test_Application_UserDeleteByID(t *testing.T) {
+	resetDB(t)
 	app.Router.DELETE("/users/:id", app.UserDeleteByID)
 
 	t.Run("should return 204 when user is deleted", func(t *testing.T) {
-		req := addLoggerToContext(httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+		req := addLoggerToContext(httptest.NewRequest(http.MethodDelete, "/users/3", nil))
 		w := httptest.NewRecorder()
 		app.Router.ServeHTTP(w, req)
 
@@ -238,8 +448,8 @@ func Test_Application_UserDeleteByID(t *testing.T) {
 		defer func() {
 			inmemory.InMemoryUserDeleteByIDFn = oldUserDeleteByIDFunc
 		}()
-		inmemory.InMemoryUserDeleteByIDFn = func(ctx context.Context, id uint64) error {
-			return &ent.NotFoundError{}
+		inmemory.InMemoryUserDeleteByIDFn = func(ctx context.Context, id uint64, opts models.DeleteOptions) error {
+			return database.ErrNotFound
 		}
 
 		req := addLoggerToContext(httptest.NewRequest(http.MethodDelete, "/users/1", nil))
@@ -264,7 +474,7 @@ func Test_Application_UserDeleteByID(t *testing.T) {
 		defer func() {
 			inmemory.InMemoryUserDeleteByIDFn = oldUserDeleteByIDFunc
 		}()
-		inmemory.InMemoryUserDeleteByIDFn = func(ctx context.Context, id uint64) error {
+		inmemory.InMemoryUserDeleteByIDFn = func(ctx context.Context, id uint64, opts models.DeleteOptions) error {
 			return errors.New("You've met a terrible fate, haven't you?")
 		}
 
@@ -275,13 +485,163 @@ func Test_Application_UserDeleteByID(t *testing.T) {
 		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 		snaps.MatchJSON(t, w.Body.String())
 	})
+
+	t.Run("should pass If-Match to the DB", func(t *testing.T) {
+		oldUserDeleteByIDFunc := inmemory.InMemoryUserDeleteByIDFn
+		defer func() {
+			inmemory.InMemoryUserDeleteByIDFn = oldUserDeleteByIDFunc
+		}()
+		var received models.DeleteOptions
+		inmemory.InMemoryUserDeleteByIDFn = func(ctx context.Context, id uint64, opts models.DeleteOptions) error {
+			received = opts
+			return nil
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+		req.Header.Set("If-Match", `"1735732800000000"`)
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.True(t, received.IfUpdatedAt.Equal(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)))
+	})
+
+	t.Run("should not add a condition when If-Match is *", func(t *testing.T) {
+		oldUserDeleteByIDFunc := inmemory.InMemoryUserDeleteByIDFn
+		defer func() {
+			inmemory.InMemoryUserDeleteByIDFn = oldUserDeleteByIDFunc
+		}()
+		var received models.DeleteOptions
+		inmemory.InMemoryUserDeleteByIDFn = func(ctx context.Context, id uint64, opts models.DeleteOptions) error {
+			received = opts
+			return nil
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+		req.Header.Set("If-Match", "*")
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Nil(t, received.IfUpdatedAt)
+	})
+
+	t.Run("should return 412 when If-Match doesn't match", func(t *testing.T) {
+		oldUserDeleteByIDFunc := inmemory.InMemoryUserDeleteByIDFn
+		defer func() {
+			inmemory.InMemoryUserDeleteByIDFn = oldUserDeleteByIDFunc
+		}()
+		inmemory.InMemoryUserDeleteByIDFn = func(ctx context.Context, id uint64, opts models.DeleteOptions) error {
+			return database.ErrPreconditionFailed
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+		req.Header.Set("If-Match", `"1"`)
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 412 when If-Match is not one of our ETags", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+		req.Header.Set("If-Match", `W/"abc"`)
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should pass the ETag of an If-Match list that matches to the DB", func(t *testing.T) {
+		oldUserDeleteByIDFunc := inmemory.InMemoryUserDeleteByIDFn
+		oldUserGetByIDFn := inmemory.InMemoryUserGetByIDFn
+		defer func() {
+			inmemory.InMemoryUserDeleteByIDFn = oldUserDeleteByIDFunc
+			inmemory.InMemoryUserGetByIDFn = oldUserGetByIDFn
+		}()
+		updatedAt := time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+		inmemory.InMemoryUserGetByIDFn = func(ctx context.Context, id uint64, include models.UserInclude) (*models.User, error) {
+			return &models.User{ID: id, UpdatedAt: updatedAt}, nil
+		}
+		var received models.DeleteOptions
+		inmemory.InMemoryUserDeleteByIDFn = func(ctx context.Context, id uint64, opts models.DeleteOptions) error {
+			received = opts
+			return nil
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+		req.Header.Set("If-Match", `"1", W/"1735732800000000", "1735732800000000"`)
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.True(t, received.IfUpdatedAt.Equal(updatedAt))
+	})
+
+	t.Run("should return 412 when no ETag of an If-Match list matches", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+		req.Header.Set("If-Match", `"1", "2"`)
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should pass cascade to the DB", func(t *testing.T) {
+		oldUserDeleteByIDFunc := inmemory.InMemoryUserDeleteByIDFn
+		defer func() {
+			inmemory.InMemoryUserDeleteByIDFn = oldUserDeleteByIDFunc
+		}()
+		var received models.DeleteOptions
+		inmemory.InMemoryUserDeleteByIDFn = func(ctx context.Context, id uint64, opts models.DeleteOptions) error {
+			received = opts
+			return nil
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodDelete, "/users/1?cascade=true", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.True(t, received.Cascade)
+	})
+
+	t.Run("should return 409 with the blocking posts when user has posts", func(t *testing.T) {
+		oldUserDeleteByIDFunc := inmemory.InMemoryUserDeleteByIDFn
+		defer func() {
+			inmemory.InMemoryUserDeleteByIDFn = oldUserDeleteByIDFunc
+		}()
+		inmemory.InMemoryUserDeleteByIDFn = func(ctx context.Context, id uint64, opts models.DeleteOptions) error {
+			return &database.UserHasPostsError{Posts: 2}
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 400 when cascade is not a boolean", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodDelete, "/users/1?cascade=please", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
 }
 
 func Test_Application_UserUpdateByID(t *testing.T) {
+	resetDB(t)
 	app.Router.PUT("/users/:id", app.UserUpdateByID)
 
 	t.Run("should return 200 when user is updated", func(t *testing.T) {
-		req := addLoggerToContext(httptest.NewRequest(http.MethodPut, "/users/1", strings.NewReader(`{"name":"Daniel Levy Moreno","email":"danielmorenolevy@gmail.com"}`)))
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPut, "/users/2", strings.NewReader(`{"name":"Daniel Levy Moreno","email":"danielmorenolevy@gmail.com"}`)))
 		w := httptest.NewRecorder()
 		app.Router.ServeHTTP(w, req)
 
@@ -313,9 +673,9 @@ func Test_Application_UserUpdateByID(t *testing.T) {
 			inmemory.InMemoryUserUpdateFn = oldUserUpdateFunc
 		}()
 		inmemory.InMemoryUserUpdateFn = func(ctx context.Context, user models.UserUpdate) (*models.User, error) {
-			return nil, &ent.NotFoundError{}
+			return nil, database.ErrNotFound
 		}
-		req := addLoggerToContext(httptest.NewRequest(http.MethodPut, "/users/1", strings.NewReader(`{"name":"Daniel Levy Moreno","email":"danielmorenolevy@gmail.com"}`)))
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPut, "/users/2", strings.NewReader(`{"name":"Daniel Levy Moreno","email":"danielmorenolevy@gmail.com"}`)))
 		w := httptest.NewRecorder()
 		app.Router.ServeHTTP(w, req)
 
@@ -329,9 +689,9 @@ func Test_Application_UserUpdateByID(t *testing.T) {
 			inmemory.InMemoryUserUpdateFn = oldUserUpdateFunc
 		}()
 		inmemory.InMemoryUserUpdateFn = func(ctx context.Context, user models.UserUpdate) (*models.User, error) {
-			return nil, &ent.ConstraintError{}
+			return nil, &database.ConflictError{Field: "email"}
 		}
-		req := addLoggerToContext(httptest.NewRequest(http.MethodPut, "/users/1", strings.NewReader(`{"name":"Daniel Levy Moreno","email":"danielmorenolevy@gmail.com"}`)))
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPut, "/users/2", strings.NewReader(`{"name":"Daniel Levy Moreno","email":"danielmorenolevy@gmail.com"}`)))
 		w := httptest.NewRecorder()
 		app.Router.ServeHTTP(w, req)
 
@@ -348,20 +708,398 @@ func Test_Application_UserUpdateByID(t *testing.T) {
 			return nil, errors.New("You've met a terrible fate, haven't you?")
 		}
 
-		req := addLoggerToContext(httptest.NewRequest(http.MethodPut, "/users/1", strings.NewReader(`{"name":"Daniel Levy Moreno","email":"danielmorenolevy@gmail.com"}`)))
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPut, "/users/2", strings.NewReader(`{"name":"Daniel Levy Moreno","email":"danielmorenolevy@gmail.com"}`)))
 		w := httptest.NewRecorder()
 		app.Router.ServeHTTP(w, req)
 		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 		snaps.MatchJSON(t, w.Body.String())
 	})
-}
 
-// POSTS
-func Test_Application_PostCreate(t *testing.T) {
-	app.Router.POST("/posts", app.PostCreate)
+	t.Run("should return the new ETag when user is updated", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPut, "/users/2", strings.NewReader(`{"name":"Daniel Levy Moreno","email":"danielmorenolevy@gmail.com"}`)))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
 
-	tests := []struct {
-		Name        string
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"1735732800000000"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("should return 412 when If-Match doesn't match", func(t *testing.T) {
+		oldUserUpdateFunc := inmemory.InMemoryUserUpdateFn
+		defer func() {
+			inmemory.InMemoryUserUpdateFn = oldUserUpdateFunc
+		}()
+		inmemory.InMemoryUserUpdateFn = func(ctx context.Context, u models.UserUpdate) (*models.User, error) {
+			return nil, database.ErrPreconditionFailed
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPut, "/users/2", strings.NewReader(`{"name":"Daniel Levy Moreno","email":"danielmorenolevy@gmail.com"}`)))
+		req.Header.Set("If-Match", `"1"`)
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+}
+
+func Test_Application_UserPostGetAll(t *testing.T) {
+	resetDB(t)
+	app.Router.GET("/users/:id/posts", app.UserPostGetAll)
+
+	tests := []struct {
+		Name       string
+		Path       string
+		StatusCode int
+	}{
+		{"should return 200 with the user posts", "/users/1/posts", http.StatusOK},
+		{"should return the first page", "/users/1/posts?limit=1", http.StatusOK},
+		{"should return the last page without next cursor", "/users/1/posts?limit=1&after=" + *encodeCursor(&models.Cursor{ID: 1}), http.StatusOK},
+		{"should return 400 when id is malformed", "/users/hahaha/posts", http.StatusBadRequest},
+		{"should return 400 when filtering by user_id", "/users/1/posts?user_id=2", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			req := addLoggerToContext(httptest.NewRequest(http.MethodGet, tt.Path, nil))
+			w := httptest.NewRecorder()
+			app.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.StatusCode, w.Code)
+			snaps.MatchJSON(t, w.Body.String())
+		})
+	}
+
+	t.Run("should return 404 when user is not found", func(t *testing.T) {
+		oldUserGetPostsFunc := inmemory.InMemoryUserGetPostsFn
+		defer func() {
+			inmemory.InMemoryUserGetPostsFn = oldUserGetPostsFunc
+		}()
+		inmemory.InMemoryUserGetPostsFn = func(ctx context.Context, id uint64, query models.PostQuery) (*models.Page[*models.Post], error) {
+			return nil, database.ErrNotFound
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/users/1/posts", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 503 when unexpected error happens", func(t *testing.T) {
+		oldUserGetPostsFunc := inmemory.InMemoryUserGetPostsFn
+		defer func() {
+			inmemory.InMemoryUserGetPostsFn = oldUserGetPostsFunc
+		}()
+		inmemory.InMemoryUserGetPostsFn = func(ctx context.Context, id uint64, query models.PostQuery) (*models.Page[*models.Post], error) {
+			return nil, errors.New("You've met a terrible fate, haven't you?")
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/users/1/posts", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+}
+
+func Test_Application_UserPostCreate(t *testing.T) {
+	resetDB(t)
+	app.Router.POST("/users/:id/posts", app.UserPostCreate)
+
+	tests := []struct {
+		Name        string
+		Path        string
+		StatusCode  int
+		RequestBody string
+	}{
+		{
+			"should return 201 if post is created on DB",
+			"/users/1/posts",
+			http.StatusCreated,
+			`{"title":"Post Title","content":"Post Content"}`,
+		},
+		{
+			"should return 400 when id is malformed",
+			"/users/hahaha/posts",
+			http.StatusBadRequest,
+			`{"title":"Post Title","content":"Post Content"}`,
+		},
+		{
+			"should return 422 if post is malformed",
+			"/users/1/posts",
+			http.StatusUnprocessableEntity,
+			`{}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			reader := strings.NewReader(tt.RequestBody)
+			req := addLoggerToContext(httptest.NewRequest(http.MethodPost, tt.Path, reader))
+			w := httptest.NewRecorder()
+			app.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.StatusCode, w.Code)
+			snaps.MatchJSON(t, w.Body.String())
+		})
+	}
+
+	t.Run("should return 404 when user is not found", func(t *testing.T) {
+		oldPostCreateFn := inmemory.InMemoryPostCreateFn
+		defer func() {
+			inmemory.InMemoryPostCreateFn = oldPostCreateFn
+		}()
+		inmemory.InMemoryPostCreateFn = func(ctx context.Context, p models.Post) (*models.Post, error) {
+			return nil, database.ErrForeignKey
+		}
+
+		reader := strings.NewReader(`{"title":"Post Title","content":"Post Content"}`)
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/users/1/posts", reader))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 503 if unknown error occurs", func(t *testing.T) {
+		oldPostCreateFn := inmemory.InMemoryPostCreateFn
+		defer func() {
+			inmemory.InMemoryPostCreateFn = oldPostCreateFn
+		}()
+		inmemory.InMemoryPostCreateFn = func(ctx context.Context, p models.Post) (*models.Post, error) {
+			return nil, errors.New("something terrible happened")
+		}
+
+		reader := strings.NewReader(`{"title":"Post Title","content":"Post Content"}`)
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/users/1/posts", reader))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 404 when user is deleted", func(t *testing.T) {
+		oldPostCreateFn := inmemory.InMemoryPostCreateFn
+		defer func() {
+			inmemory.InMemoryPostCreateFn = oldPostCreateFn
+		}()
+		inmemory.InMemoryPostCreateFn = func(ctx context.Context, post models.Post) (*models.Post, error) {
+			return nil, database.ErrUserDeleted
+		}
+
+		reader := strings.NewReader(`{"title":"Post Title","content":"Post Content"}`)
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/users/1/posts", reader))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+}
+
+func Test_Application_UserPatchByID(t *testing.T) {
+	resetDB(t)
+	app.Router.PATCH("/users/:id", app.UserPatchByID)
+
+	tests := []struct {
+		Name        string
+		Path        string
+		ContentType string
+		RequestBody string
+		StatusCode  int
+	}{
+		{"should return 200 when user is patched", "/users/1", "application/merge-patch+json", `{"name":"New Name"}`, http.StatusOK},
+		{"should accept application/json", "/users/1", "application/json", `{"name":"New Name"}`, http.StatusOK},
+		{"should return 200 when patch is empty", "/users/1", "application/merge-patch+json", `{}`, http.StatusOK},
+		{"should return 400 when id is malformed", "/users/hahaha", "application/merge-patch+json", `{"name":"New Name"}`, http.StatusBadRequest},
+		{"should return 415 when content type is not JSON", "/users/1", "text/plain", `{"name":"New Name"}`, http.StatusUnsupportedMediaType},
+		{"should return 422 when removing a field with null", "/users/1", "application/merge-patch+json", `{"email":null}`, http.StatusUnprocessableEntity},
+		{"should return 422 when email is invalid", "/users/1", "application/merge-patch+json", `{"email":"not-an-email"}`, http.StatusUnprocessableEntity},
+		{"should return 422 when setting an empty value", "/users/1", "application/merge-patch+json", `{"name":""}`, http.StatusUnprocessableEntity},
+		{"should return 422 when patch is not an object", "/users/1", "application/merge-patch+json", `[]`, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			req := addLoggerToContext(httptest.NewRequest(http.MethodPatch, tt.Path, strings.NewReader(tt.RequestBody)))
+			req.Header.Set("Content-Type", tt.ContentType)
+			w := httptest.NewRecorder()
+			app.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.StatusCode, w.Code)
+			snaps.MatchJSON(t, w.Body.String())
+		})
+	}
+
+	t.Run("should only send the fields present in the patch", func(t *testing.T) {
+		oldUserPatchFn := inmemory.InMemoryUserPatchFn
+		defer func() {
+			inmemory.InMemoryUserPatchFn = oldUserPatchFn
+		}()
+		var received models.UserPatch
+		inmemory.InMemoryUserPatchFn = func(ctx context.Context, patch models.UserPatch) (*models.User, error) {
+			received = patch
+			return &models.User{ID: *patch.ID, UpdatedAt: testClock()}, nil
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(`{"name":"New Name"}`)))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, uint64(1), *received.ID)
+		assert.Equal(t, "New Name", *received.Name)
+		assert.Nil(t, received.Email)
+	})
+
+	t.Run("should return 404 when user is not found", func(t *testing.T) {
+		oldUserPatchFn := inmemory.InMemoryUserPatchFn
+		defer func() {
+			inmemory.InMemoryUserPatchFn = oldUserPatchFn
+		}()
+		inmemory.InMemoryUserPatchFn = func(ctx context.Context, patch models.UserPatch) (*models.User, error) {
+			return nil, database.ErrNotFound
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(`{"name":"New Name"}`)))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 409 when email is already in use", func(t *testing.T) {
+		oldUserPatchFn := inmemory.InMemoryUserPatchFn
+		defer func() {
+			inmemory.InMemoryUserPatchFn = oldUserPatchFn
+		}()
+		inmemory.InMemoryUserPatchFn = func(ctx context.Context, patch models.UserPatch) (*models.User, error) {
+			return nil, &database.ConflictError{Field: "email"}
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(`{"name":"New Name"}`)))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 503 when unexpected error happens", func(t *testing.T) {
+		oldUserPatchFn := inmemory.InMemoryUserPatchFn
+		defer func() {
+			inmemory.InMemoryUserPatchFn = oldUserPatchFn
+		}()
+		inmemory.InMemoryUserPatchFn = func(ctx context.Context, patch models.UserPatch) (*models.User, error) {
+			return nil, errors.New("You've met a terrible fate, haven't you?")
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(`{"name":"New Name"}`)))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should pass If-Match to the DB", func(t *testing.T) {
+		oldUserPatchFn := inmemory.InMemoryUserPatchFn
+		defer func() {
+			inmemory.InMemoryUserPatchFn = oldUserPatchFn
+		}()
+		var received models.UserPatch
+		inmemory.InMemoryUserPatchFn = func(ctx context.Context, patch models.UserPatch) (*models.User, error) {
+			received = patch
+			return &models.User{ID: *patch.ID, UpdatedAt: testClock()}, nil
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(`{"name":"New Name"}`)))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", `"1735732800000000"`)
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, received.IfUpdatedAt.Equal(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)))
+		assert.Equal(t, `"1735732800000000"`, w.Header().Get("ETag"))
+	})
+}
+
+func Test_Application_UserRestoreByID(t *testing.T) {
+	resetDB(t)
+	app.Router.POST("/users/:id/restore", app.UserRestoreByID)
+
+	t.Run("should return 200 with the restored user", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/users/1/restore", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"1735732800000000"`, w.Header().Get("ETag"))
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 400 when id is malformed", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/users/hahaha/restore", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 404 when user is not found", func(t *testing.T) {
+		oldUserRestoreByIDFn := inmemory.InMemoryUserRestoreByIDFn
+		defer func() {
+			inmemory.InMemoryUserRestoreByIDFn = oldUserRestoreByIDFn
+		}()
+		inmemory.InMemoryUserRestoreByIDFn = func(ctx context.Context, id uint64) (*models.User, error) {
+			return nil, database.ErrNotFound
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/users/1/restore", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 503 when unexpected error happens", func(t *testing.T) {
+		oldUserRestoreByIDFn := inmemory.InMemoryUserRestoreByIDFn
+		defer func() {
+			inmemory.InMemoryUserRestoreByIDFn = oldUserRestoreByIDFn
+		}()
+		inmemory.InMemoryUserRestoreByIDFn = func(ctx context.Context, id uint64) (*models.User, error) {
+			return nil, errors.New("You've met a terrible fate, haven't you?")
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/users/1/restore", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+}
+
+// POSTS
+func Test_Application_PostCreate(t *testing.T) {
+	resetDB(t)
+	app.Router.POST("/posts", app.PostCreate)
+
+	tests := []struct {
+		Name        string
 		StatusCode  int
 		RequestBody string
 	}{
@@ -377,10 +1115,142 @@ func Test_Application_PostCreate(t *testing.T) {
 		},
 	}
 
-	for _, tt := range tests {
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			reader := strings.NewReader(tt.RequestBody)
+			req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/posts", reader))
+			w := httptest.NewRecorder()
+			app.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.StatusCode, w.Code)
+			snaps.MatchJSON(t, w.Body.String())
+		})
+	}
+
+	t.Run("should return 503 if unknown error occurs", func(t *testing.T) {
+		oldPostCreateFn := inmemory.InMemoryPostCreateFn
+		defer func() {
+			inmemory.InMemoryPostCreateFn = oldPostCreateFn
+		}()
+
+		inmemory.InMemoryPostCreateFn = func(ctx context.Context, p models.Post) (*models.Post, error) {
+			return nil, errors.New("something terrible happened")
+		}
+
+		reader := strings.NewReader(`{"title":"Post Title","content":"Post Content","user_id":1}`)
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/posts", reader))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, 503, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+}
+
+func Test_Application_PostCreateBatch(t *testing.T) {
+	resetDB(t)
+	customMethod(app.Router, "/posts", "batch", app.PostCreateBatch)
+
+	tests := []struct {
+		Name        string
+		StatusCode  int
+		RequestBody string
+	}{
+		{
+			"should return 201 if every post is created on DB",
+			http.StatusCreated,
+			`{"items":[{"title":"Post Title","content":"Post Content","user_id":1},{"title":"Post Title","content":"Post Content","user_id":3}]}`,
+		},
+		{
+			"should return 409 and create none if a user doesn't exist",
+			http.StatusConflict,
+			`{"items":[{"title":"Post Title","content":"Post Content","user_id":1},{"title":"Post Title","content":"Post Content","user_id":999}]}`,
+		},
+		{
+			"should return 207 with the outcome of each post in partial mode",
+			http.StatusMultiStatus,
+			`{"mode":"partial","items":[{"title":"Post Title","content":"Post Content","user_id":999},{"title":"Post Title","content":"Post Content","user_id":2},{"title":"Post Title","user_id":2}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			reader := strings.NewReader(tt.RequestBody)
+			req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/posts:batch", reader))
+			w := httptest.NewRecorder()
+			app.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.StatusCode, w.Code)
+			snaps.MatchJSON(t, w.Body.String())
+		})
+	}
+
+	t.Run("should return 503 if unknown error occurs", func(t *testing.T) {
+		oldPostCreateBulkFn := inmemory.InMemoryPostCreateBulkFn
+		defer func() {
+			inmemory.InMemoryPostCreateBulkFn = oldPostCreateBulkFn
+		}()
+		inmemory.InMemoryPostCreateBulkFn = func(ctx context.Context, posts []models.Post, opts models.BulkOptions) ([]*models.Post, error) {
+			return nil, errors.New("something terrible happened")
+		}
+
+		reader := strings.NewReader(`{"items":[{"title":"Post Title","content":"Post Content","user_id":1}]}`)
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/posts:batch", reader))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 409 if the user is deleted by another request meanwhile", func(t *testing.T) {
+		oldPostCreateBulkFn := inmemory.InMemoryPostCreateBulkFn
+		defer func() {
+			inmemory.InMemoryPostCreateBulkFn = oldPostCreateBulkFn
+		}()
+		inmemory.InMemoryPostCreateBulkFn = func(ctx context.Context, posts []models.Post, opts models.BulkOptions) ([]*models.Post, error) {
+			return nil, database.ErrForeignKey
+		}
+
+		reader := strings.NewReader(`{"items":[{"title":"Post Title","content":"Post Content","user_id":1}]}`)
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/posts:batch", reader))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+}
+
+func Test_Application_PostGetAll(t *testing.T) {
+	resetDB(t)
+	app.Router.GET("/posts", app.PostGetAll)
+
+	t.Run("should return 200 with all data", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/posts", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	pageTests := []struct {
+		Name       string
+		Query      string
+		StatusCode int
+	}{
+		{"should return the first page", "?limit=1", http.StatusOK},
+		{"should return a middle page", "?limit=1&after=" + *encodeCursor(&models.Cursor{ID: 1}), http.StatusOK},
+		{"should return the last page without next cursor", "?limit=1&after=" + *encodeCursor(&models.Cursor{ID: 2}), http.StatusOK},
+		{"should return 400 when limit is out of range", "?limit=0", http.StatusBadRequest},
+		{"should return 400 when limit is not a number", "?limit=ten", http.StatusBadRequest},
+		{"should return 400 when cursor is malformed", "?after=not-a-cursor", http.StatusBadRequest},
+	}
+
+	for _, tt := range pageTests {
 		t.Run(tt.Name, func(t *testing.T) {
-			reader := strings.NewReader(tt.RequestBody)
-			req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/posts", reader))
+			req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/posts"+tt.Query, nil))
 			w := httptest.NewRecorder()
 			app.Router.ServeHTTP(w, req)
 
@@ -389,31 +1259,66 @@ func Test_Application_PostCreate(t *testing.T) {
 		})
 	}
 
-	t.Run("should return 503 if unknown error occurs", func(t *testing.T) {
-		oldPostCreateFn := inmemory.InMemoryPostCreateFn
+	t.Run("should pass filters and sorting to the DB", func(t *testing.T) {
+		oldPostGetAllFunc := inmemory.InMemoryPostGetAllFn
 		defer func() {
-			inmemory.InMemoryPostCreateFn = oldPostCreateFn
+			inmemory.InMemoryPostGetAllFn = oldPostGetAllFunc
 		}()
-
-		inmemory.InMemoryPostCreateFn = func(ctx context.Context, p models.Post) (*models.Post, error) {
-			return nil, errors.New("something terrible happened")
+		var received models.PostQuery
+		inmemory.InMemoryPostGetAllFn = func(ctx context.Context, query models.PostQuery) (*models.Page[*models.Post], error) {
+			received = query
+			return &models.Page[*models.Post]{Items: []*models.Post{}}, nil
 		}
 
-		reader := strings.NewReader(`{"title":"Post Title","content":"Post Content","user_id":1}`)
-		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/posts", reader))
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/posts?user_id=1&created_after=2025-01-01T00:00:00Z&created_before=2025-02-01T00:00:00Z&title_contains=cool&sort=-created_at,title", nil))
 		w := httptest.NewRecorder()
 		app.Router.ServeHTTP(w, req)
 
-		assert.Equal(t, 503, w.Code)
-		snaps.MatchJSON(t, w.Body.String())
+		userID := uint64(1)
+		createdAfter := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		createdBefore := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, models.PostQuery{
+			Pagination:    models.Pagination{Limit: defaultPageLimit},
+			UserID:        &userID,
+			CreatedAfter:  &createdAfter,
+			CreatedBefore: &createdBefore,
+			TitleContains: "cool",
+			Sort: []models.SortField{
+				{Field: "created_at", Desc: true},
+				{Field: "title"},
+			},
+		}, received)
 	})
-}
 
-func Test_Application_PostGetAll(t *testing.T) {
-	app.Router.GET("/posts", app.PostGetAll)
+	queryTests := []struct {
+		Name  string
+		Query string
+	}{
+		{"should return 400 when parameter is unknown", "?author=1"},
+		{"should return 400 when user_id is not a number", "?user_id=me"},
+		{"should return 400 when created_after is not a timestamp", "?created_after=yesterday"},
+		{"should return 400 when created_before is not a timestamp", "?created_before=2025-13-01"},
+		{"should return 400 when title_contains is empty", "?title_contains="},
+		{"should return 400 when sorting by unknown field", "?sort=content"},
+		{"should return 400 when sort field is repeated", "?sort=title,-title"},
+		{"should return 400 when cursor does not match sort", "?sort=title&after=" + *encodeCursor(&models.Cursor{ID: 1})},
+		{"should return 400 when include is unknown", "?include=posts"},
+	}
 
-	t.Run("should return 200 with all data", func(t *testing.T) {
-		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/posts", nil))
+	for _, tt := range queryTests {
+		t.Run(tt.Name, func(t *testing.T) {
+			req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/posts"+tt.Query, nil))
+			w := httptest.NewRecorder()
+			app.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			snaps.MatchJSON(t, w.Body.String())
+		})
+	}
+
+	t.Run("should embed users with include=user", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/posts?include=user", nil))
 		w := httptest.NewRecorder()
 		app.Router.ServeHTTP(w, req)
 
@@ -426,8 +1331,8 @@ func Test_Application_PostGetAll(t *testing.T) {
 		defer func() {
 			inmemory.InMemoryPostGetAllFn = oldPostGetAllFunc
 		}()
-		inmemory.InMemoryPostGetAllFn = func(ctx context.Context) ([]*models.Post, error) {
-			return []*models.Post{}, nil
+		inmemory.InMemoryPostGetAllFn = func(ctx context.Context, query models.PostQuery) (*models.Page[*models.Post], error) {
+			return &models.Page[*models.Post]{Items: []*models.Post{}}, nil
 		}
 
 		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/posts", nil))
@@ -443,7 +1348,7 @@ func Test_Application_PostGetAll(t *testing.T) {
 		defer func() {
 			inmemory.InMemoryPostGetAllFn = oldPostGetAllFunc
 		}()
-		inmemory.InMemoryPostGetAllFn = func(ctx context.Context) ([]*models.Post, error) {
+		inmemory.InMemoryPostGetAllFn = func(ctx context.Context, query models.PostQuery) (*models.Page[*models.Post], error) {
 			return nil, errors.New("You've met a terrible fate, haven't you?")
 		}
 
@@ -457,6 +1362,7 @@ func Test_Application_PostGetAll(t *testing.T) {
 }
 
 func Test_Application_PostGetByID(t *testing.T) {
+	resetDB(t)
 	app.Router.GET("/posts/:id", app.PostGetByID)
 
 	t.Run("should return 200 with post data", func(t *testing.T) {
@@ -468,6 +1374,24 @@ func Test_Application_PostGetByID(t *testing.T) {
 		snaps.MatchJSON(t, w.Body.String())
 	})
 
+	t.Run("should embed user with include=user", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/posts/1?include=user", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 400 when include is unknown", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/posts/1?include=posts", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
 	t.Run("should return 400 when id is malformed", func(t *testing.T) {
 		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/posts/hahaha", nil))
 		w := httptest.NewRecorder()
@@ -482,8 +1406,8 @@ func Test_Application_PostGetByID(t *testing.T) {
 		defer func() {
 			inmemory.InMemoryPostGetByIDFn = oldPostGetByIDFunc
 		}()
-		inmemory.InMemoryPostGetByIDFn = func(ctx context.Context, id uint64) (*models.Post, error) {
-			return nil, &ent.NotFoundError{}
+		inmemory.InMemoryPostGetByIDFn = func(ctx context.Context, id uint64, include models.PostInclude) (*models.Post, error) {
+			return nil, database.ErrNotFound
 		}
 
 		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/posts/1", nil))
@@ -499,7 +1423,7 @@ func Test_Application_PostGetByID(t *testing.T) {
 		defer func() {
 			inmemory.InMemoryPostGetByIDFn = oldPostGetByIDFunc
 		}()
-		inmemory.InMemoryPostGetByIDFn = func(ctx context.Context, id uint64) (*models.Post, error) {
+		inmemory.InMemoryPostGetByIDFn = func(ctx context.Context, id uint64, include models.PostInclude) (*models.Post, error) {
 			return nil, errors.New("You've met a terrible fate, haven't you?")
 		}
 
@@ -510,9 +1434,30 @@ func Test_Application_PostGetByID(t *testing.T) {
 		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 		snaps.MatchJSON(t, w.Body.String())
 	})
+
+	t.Run("should return an ETag derived from updated_at", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/posts/1", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"1735732800000000"`, w.Header().Get("ETag"))
+		assert.Equal(t, "Wed, 01 Jan 2025 12:00:00 GMT", w.Header().Get("Last-Modified"))
+	})
+
+	t.Run("should leave validators to conditionalGET with include=user", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, "/posts/1?include=user", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("ETag"))
+		assert.Empty(t, w.Header().Get("Last-Modified"))
+	})
 }
 
 func Test_Application_PostDeleteByID(t *testing.T) {
+	resetDB(t)
 	app.Router.DELETE("/posts/:id", app.PostDeleteByID)
 
 	t.Run("should return 204 when post is deleted", func(t *testing.T) {
@@ -529,8 +1474,8 @@ func Test_Application_PostDeleteByID(t *testing.T) {
 		defer func() {
 			inmemory.InMemoryPostDeleteByIDFn = oldPostDeleteByIDFunc
 		}()
-		inmemory.InMemoryPostDeleteByIDFn = func(ctx context.Context, id uint64) error {
-			return &ent.NotFoundError{}
+		inmemory.InMemoryPostDeleteByIDFn = func(ctx context.Context, id uint64, opts models.DeleteOptions) error {
+			return database.ErrNotFound
 		}
 
 		req := addLoggerToContext(httptest.NewRequest(http.MethodDelete, "/posts/1", nil))
@@ -555,7 +1500,7 @@ func Test_Application_PostDeleteByID(t *testing.T) {
 		defer func() {
 			inmemory.InMemoryPostDeleteByIDFn = oldPostDeleteByIDFunc
 		}()
-		inmemory.InMemoryPostDeleteByIDFn = func(ctx context.Context, id uint64) error {
+		inmemory.InMemoryPostDeleteByIDFn = func(ctx context.Context, id uint64, opts models.DeleteOptions) error {
 			return errors.New("You've met a terrible fate, haven't you?")
 		}
 
@@ -566,9 +1511,93 @@ func Test_Application_PostDeleteByID(t *testing.T) {
 		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 		snaps.MatchJSON(t, w.Body.String())
 	})
+
+	t.Run("should return 412 when If-Match doesn't match", func(t *testing.T) {
+		oldPostDeleteByIDFunc := inmemory.InMemoryPostDeleteByIDFn
+		defer func() {
+			inmemory.InMemoryPostDeleteByIDFn = oldPostDeleteByIDFunc
+		}()
+		inmemory.InMemoryPostDeleteByIDFn = func(ctx context.Context, id uint64, opts models.DeleteOptions) error {
+			return database.ErrPreconditionFailed
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodDelete, "/posts/1", nil))
+		req.Header.Set("If-Match", `"1"`)
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+}
+
+func Test_Application_PostDeleteAll(t *testing.T) {
+	resetDB(t)
+	app.Router.DELETE("/posts", app.PostDeleteAll)
+
+	tests := []struct {
+		Name       string
+		Path       string
+		StatusCode int
+	}{
+		{
+			"should return 200 with how many posts of the user were deleted",
+			"/posts?user_id=1",
+			http.StatusOK,
+		},
+		{
+			"should return 404 when user is not found",
+			"/posts?user_id=999",
+			http.StatusNotFound,
+		},
+		{
+			"should return 400 when user_id is missing",
+			"/posts",
+			http.StatusBadRequest,
+		},
+		{
+			"should return 400 when user_id is malformed",
+			"/posts?user_id=hahaha",
+			http.StatusBadRequest,
+		},
+		{
+			"should return 400 on unknown parameters",
+			"/posts?user_id=1&title_contains=coolio",
+			http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			req := addLoggerToContext(httptest.NewRequest(http.MethodDelete, tt.Path, nil))
+			w := httptest.NewRecorder()
+			app.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.StatusCode, w.Code)
+			snaps.MatchJSON(t, w.Body.String())
+		})
+	}
+
+	t.Run("should return 503 if unknown error occurs", func(t *testing.T) {
+		oldPostDeleteByUserIDFn := inmemory.InMemoryPostDeleteByUserIDFn
+		defer func() {
+			inmemory.InMemoryPostDeleteByUserIDFn = oldPostDeleteByUserIDFn
+		}()
+		inmemory.InMemoryPostDeleteByUserIDFn = func(ctx context.Context, userID uint64) (int, error) {
+			return 0, errors.New("something terrible happened")
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodDelete, "/posts?user_id=1", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
 }
 
 func Test_Application_PostUpdateByID(t *testing.T) {
+	resetDB(t)
 	app.Router.PUT("/posts/:id", app.PostUpdateByID)
 
 	t.Run("should return 200 when post is updated", func(t *testing.T) {
@@ -595,7 +1624,7 @@ func Test_Application_PostUpdateByID(t *testing.T) {
 			inmemory.InMemoryPostUpdateFn = oldPostUpdateFn
 		}()
 		inmemory.InMemoryPostUpdateFn = func(ctx context.Context, post models.PostUpdate) (*models.Post, error) {
-			return nil, &ent.NotFoundError{}
+			return nil, database.ErrNotFound
 		}
 		req := addLoggerToContext(httptest.NewRequest(http.MethodPut, "/posts/1", strings.NewReader(`{"title":"Post Title","content":"Post Content","user_id":1}`)))
 		w := httptest.NewRecorder()
@@ -611,7 +1640,7 @@ func Test_Application_PostUpdateByID(t *testing.T) {
 			inmemory.InMemoryPostUpdateFn = oldPostUpdateFn
 		}()
 		inmemory.InMemoryPostUpdateFn = func(ctx context.Context, post models.PostUpdate) (*models.Post, error) {
-			return nil, &ent.ConstraintError{}
+			return nil, database.ErrForeignKey
 		}
 		req := addLoggerToContext(httptest.NewRequest(http.MethodPut, "/posts/1", strings.NewReader(`{"title":"Post Title","content":"Post Content","user_id":1}`)))
 		w := httptest.NewRecorder()
@@ -645,4 +1674,229 @@ func Test_Application_PostUpdateByID(t *testing.T) {
 		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 		snaps.MatchJSON(t, w.Body.String())
 	})
+
+	t.Run("should pass If-Match to the DB", func(t *testing.T) {
+		oldPostUpdateFunc := inmemory.InMemoryPostUpdateFn
+		defer func() {
+			inmemory.InMemoryPostUpdateFn = oldPostUpdateFunc
+		}()
+		var received models.PostUpdate
+		inmemory.InMemoryPostUpdateFn = func(ctx context.Context, post models.PostUpdate) (*models.Post, error) {
+			received = post
+			return &models.Post{ID: *post.ID, UpdatedAt: testClock()}, nil
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPut, "/posts/1", strings.NewReader(`{"title":"Post Title","content":"Post Content"}`)))
+		req.Header.Set("If-Match", `"1735732800000000"`)
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, received.IfUpdatedAt.Equal(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)))
+		assert.Equal(t, `"1735732800000000"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("should return 412 when If-Match doesn't match", func(t *testing.T) {
+		oldPostUpdateFunc := inmemory.InMemoryPostUpdateFn
+		defer func() {
+			inmemory.InMemoryPostUpdateFn = oldPostUpdateFunc
+		}()
+		inmemory.InMemoryPostUpdateFn = func(ctx context.Context, post models.PostUpdate) (*models.Post, error) {
+			return nil, database.ErrPreconditionFailed
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPut, "/posts/1", strings.NewReader(`{"title":"Post Title","content":"Post Content"}`)))
+		req.Header.Set("If-Match", `"1"`)
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+}
+
+func Test_Application_PostPatchByID(t *testing.T) {
+	resetDB(t)
+	app.Router.PATCH("/posts/:id", app.PostPatchByID)
+
+	tests := []struct {
+		Name        string
+		Path        string
+		ContentType string
+		RequestBody string
+		StatusCode  int
+	}{
+		{"should return 200 when post is patched", "/posts/1", "application/merge-patch+json", `{"title":"New Title"}`, http.StatusOK},
+		{"should accept application/json", "/posts/1", "application/json", `{"title":"New Title"}`, http.StatusOK},
+		{"should return 200 when patch is empty", "/posts/1", "application/merge-patch+json", `{}`, http.StatusOK},
+		{"should return 400 when id is malformed", "/posts/hahaha", "application/merge-patch+json", `{"title":"New Title"}`, http.StatusBadRequest},
+		{"should return 415 when content type is not JSON", "/posts/1", "text/plain", `{"title":"New Title"}`, http.StatusUnsupportedMediaType},
+		{"should return 422 when removing a field with null", "/posts/1", "application/merge-patch+json", `{"content":null}`, http.StatusUnprocessableEntity},
+		{"should return 422 when setting an empty value", "/posts/1", "application/merge-patch+json", `{"title":""}`, http.StatusUnprocessableEntity},
+		{"should return 422 when patch is not an object", "/posts/1", "application/merge-patch+json", `[]`, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			req := addLoggerToContext(httptest.NewRequest(http.MethodPatch, tt.Path, strings.NewReader(tt.RequestBody)))
+			req.Header.Set("Content-Type", tt.ContentType)
+			w := httptest.NewRecorder()
+			app.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.StatusCode, w.Code)
+			snaps.MatchJSON(t, w.Body.String())
+		})
+	}
+
+	t.Run("should only send the fields present in the patch", func(t *testing.T) {
+		oldPostPatchFn := inmemory.InMemoryPostPatchFn
+		defer func() {
+			inmemory.InMemoryPostPatchFn = oldPostPatchFn
+		}()
+		var received models.PostPatch
+		inmemory.InMemoryPostPatchFn = func(ctx context.Context, patch models.PostPatch) (*models.Post, error) {
+			received = patch
+			return &models.Post{ID: *patch.ID, UpdatedAt: testClock()}, nil
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPatch, "/posts/1", strings.NewReader(`{"title":"New Title"}`)))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, uint64(1), *received.ID)
+		assert.Equal(t, "New Title", *received.Title)
+		assert.Nil(t, received.Content)
+	})
+
+	t.Run("should return 404 when post is not found", func(t *testing.T) {
+		oldPostPatchFn := inmemory.InMemoryPostPatchFn
+		defer func() {
+			inmemory.InMemoryPostPatchFn = oldPostPatchFn
+		}()
+		inmemory.InMemoryPostPatchFn = func(ctx context.Context, patch models.PostPatch) (*models.Post, error) {
+			return nil, database.ErrNotFound
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPatch, "/posts/1", strings.NewReader(`{"title":"New Title"}`)))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 503 when unexpected error happens", func(t *testing.T) {
+		oldPostPatchFn := inmemory.InMemoryPostPatchFn
+		defer func() {
+			inmemory.InMemoryPostPatchFn = oldPostPatchFn
+		}()
+		inmemory.InMemoryPostPatchFn = func(ctx context.Context, patch models.PostPatch) (*models.Post, error) {
+			return nil, errors.New("You've met a terrible fate, haven't you?")
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPatch, "/posts/1", strings.NewReader(`{"title":"New Title"}`)))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 412 when If-Match doesn't match", func(t *testing.T) {
+		oldPostPatchFn := inmemory.InMemoryPostPatchFn
+		defer func() {
+			inmemory.InMemoryPostPatchFn = oldPostPatchFn
+		}()
+		inmemory.InMemoryPostPatchFn = func(ctx context.Context, patch models.PostPatch) (*models.Post, error) {
+			return nil, database.ErrPreconditionFailed
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPatch, "/posts/1", strings.NewReader(`{"title":"New Title"}`)))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", `"1"`)
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+}
+
+func Test_Application_PostRestoreByID(t *testing.T) {
+	resetDB(t)
+	app.Router.POST("/posts/:id/restore", app.PostRestoreByID)
+
+	t.Run("should return 200 with the restored post", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/posts/1/restore", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"1735732800000000"`, w.Header().Get("ETag"))
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 400 when id is malformed", func(t *testing.T) {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/posts/hahaha/restore", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 404 when post is not found", func(t *testing.T) {
+		oldPostRestoreByIDFn := inmemory.InMemoryPostRestoreByIDFn
+		defer func() {
+			inmemory.InMemoryPostRestoreByIDFn = oldPostRestoreByIDFn
+		}()
+		inmemory.InMemoryPostRestoreByIDFn = func(ctx context.Context, id uint64) (*models.Post, error) {
+			return nil, database.ErrNotFound
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/posts/1/restore", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 409 when the post user is deleted", func(t *testing.T) {
+		oldPostRestoreByIDFn := inmemory.InMemoryPostRestoreByIDFn
+		defer func() {
+			inmemory.InMemoryPostRestoreByIDFn = oldPostRestoreByIDFn
+		}()
+		inmemory.InMemoryPostRestoreByIDFn = func(ctx context.Context, id uint64) (*models.Post, error) {
+			return nil, database.ErrUserDeleted
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/posts/1/restore", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 503 when unexpected error happens", func(t *testing.T) {
+		oldPostRestoreByIDFn := inmemory.InMemoryPostRestoreByIDFn
+		defer func() {
+			inmemory.InMemoryPostRestoreByIDFn = oldPostRestoreByIDFn
+		}()
+		inmemory.InMemoryPostRestoreByIDFn = func(ctx context.Context, id uint64) (*models.Post, error) {
+			return nil, errors.New("You've met a terrible fate, haven't you?")
+		}
+
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, "/posts/1/restore", nil))
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
 }
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql/migrations"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/logger"
+)
+
+// How long a single dependency check may take before it counts as failing
+const healthCheckTimeout = 2 * time.Second
+
+// Measures the latency of the checks, replaced in tests
+var healthCheckSince = time.Since
+
+// Checks a dependency the API needs to serve requests, failing if it can't be
+// used
+type HealthCheckFunc func(ctx context.Context) error
+
+type healthCheck struct {
+	name  string
+	check HealthCheckFunc
+}
+
+// Outcome of a dependency check, as listed by `GET /health?verbose=1`
+type healthCheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Adds a dependency to the ones `/readyz` and `/health` check, after the
+// database
+func (a *Application) RegisterHealthCheck(name string, check HealthCheckFunc) {
+	a.healthChecks = append(a.healthChecks, healthCheck{name: name, check: check})
+}
+
+// Runs every dependency check at once, returning their outcomes in the order
+// they were registered and whether all of them passed
+func (a *Application) runHealthChecks(ctx context.Context) ([]healthCheckResult, bool) {
+	checks := append([]healthCheck{{name: "database", check: a.Health.Ping}}, a.healthChecks...)
+	results := make([]healthCheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := c.check(checkCtx)
+			results[i] = healthCheckResult{
+				Name:      c.name,
+				Status:    "OK",
+				LatencyMS: float64(healthCheckSince(start).Microseconds()) / 1000.0,
+			}
+			if err != nil {
+				results[i].Status = "failing"
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		if r.Status != "OK" {
+			return results, false
+		}
+	}
+
+	return results, true
+}
+
+// Fails while the database has migrations the code expects but are not
+// applied yet, e.g. during a rollout that is ahead of `cmd/migration up`
+func migrationsCheck(migrator *migrations.Migrator) HealthCheckFunc {
+	return func(ctx context.Context) error {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return errors.New("pending migrations: " + strings.Join(migrationNames(pending), ", "))
+		}
+
+		return nil
+	}
+}
+
+// Names of the migration files, without extension
+func migrationNames(ms []migrations.Migration) []string {
+	names := make([]string, 0, len(ms))
+	for _, m := range ms {
+		names = append(names, m.Version+"_"+m.Name)
+	}
+
+	return names
+}
+
+// Sends the readiness of the API, along with the outcome of each check if
+// `verbose`
+func (a *Application) respondReadiness(ctx *gin.Context, handler string, verbose bool) {
+	log := logger.FromContext(ctx.Request.Context()).
+		With().
+		Str("handler", handler).
+		Logger()
+
+	log.Info().
+		Msg("checking dependencies")
+
+	results, ok := a.runHealthChecks(ctx.Request.Context())
+	for _, r := range results {
+		if r.Error != "" {
+			log.Error().
+				Str("check", r.Name).
+				Str("error", r.Error).
+				Msg("dependency check failed")
+		}
+	}
+
+	detail := ""
+	switch {
+	case a.shuttingDown.Load():
+		log.Info().
+			Msg("shutting down, failing the check")
+
+		detail = "shutting down"
+	case !ok:
+		detail = "service unavailable"
+	default:
+		log.Info().
+			Msg("Health check OK")
+	}
+
+	// Failing checks are problems like every other error, the results of
+	// each check go along as an extension member
+	if detail != "" {
+		p := problem{Status: http.StatusServiceUnavailable, Detail: detail}
+		if verbose {
+			p.Extensions = map[string]any{"checks": results}
+		}
+		renderProblem(ctx, p)
+		return
+	}
+
+	body := gin.H{"status": "OK"}
+	if verbose {
+		body["checks"] = results
+	}
+
+	ctx.JSON(http.StatusOK, body)
+}
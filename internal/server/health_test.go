@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/inmemory"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/postgresql"
+)
+
+func Test_Application_probes(t *testing.T) {
+	resetDB(t)
+
+	// Every check takes 1.5ms
+	oldSince := healthCheckSince
+	healthCheckSince = func(time.Time) time.Duration {
+		return 1500 * time.Microsecond
+	}
+	defer func() {
+		healthCheckSince = oldSince
+	}()
+
+	// Copy of the app with a cache check, kept out of the other tests
+	a := app
+	a.healthChecks = nil
+	var cacheErr error
+	a.RegisterHealthCheck("cache", func(ctx context.Context) error {
+		return cacheErr
+	})
+
+	router := gin.New()
+	router.GET("/livez", a.LivenessCheck)
+	router.GET("/readyz", a.ReadinessCheck)
+	router.GET("/health", a.HealthCheck)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodGet, path, nil))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		return w
+	}
+
+	t.Run("should report every check with `verbose`", func(t *testing.T) {
+		w := get("/health?verbose=1")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should return 400 for an invalid `verbose`", func(t *testing.T) {
+		w := get("/health?verbose=please")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should fail readiness but not liveness when a dependency fails", func(t *testing.T) {
+		oldPingFunc := inmemory.InMemoryDBPingFn
+		inmemory.InMemoryDBPingFn = func(ctx context.Context) error {
+			return errors.New("connection refused")
+		}
+		defer func() {
+			inmemory.InMemoryDBPingFn = oldPingFunc
+		}()
+		cacheErr = errors.New("cache miss")
+		defer func() {
+			cacheErr = nil
+		}()
+
+		livez := get("/livez")
+		readyz := get("/readyz")
+		health := get("/health?verbose=true")
+
+		assert.Equal(t, http.StatusOK, livez.Code)
+		assert.Equal(t, http.StatusServiceUnavailable, readyz.Code)
+		assert.Equal(t, http.StatusServiceUnavailable, health.Code)
+		assert.Equal(t, problemContentType, health.Header().Get("Content-Type"))
+		snaps.MatchJSON(t, readyz.Body.String())
+		snaps.MatchJSON(t, health.Body.String())
+	})
+
+	t.Run("should fail readiness but not liveness while shutting down", func(t *testing.T) {
+		a.shuttingDown.Store(true)
+		defer a.shuttingDown.Store(false)
+
+		livez := get("/livez")
+		readyz := get("/readyz")
+
+		assert.Equal(t, http.StatusOK, livez.Code)
+		assert.Equal(t, http.StatusServiceUnavailable, readyz.Code)
+		snaps.MatchJSON(t, readyz.Body.String())
+	})
+
+	t.Run("should pass readiness and liveness when every check passes", func(t *testing.T) {
+		livez := get("/livez")
+		readyz := get("/readyz")
+
+		assert.Equal(t, http.StatusOK, livez.Code)
+		assert.Equal(t, http.StatusOK, readyz.Code)
+		snaps.MatchJSON(t, livez.Body.String())
+		snaps.MatchJSON(t, readyz.Body.String())
+	})
+}
+
+func Test_migrationsCheck(t *testing.T) {
+	l := zerolog.Nop()
+	client := postgresql.NewSQLite(":memory:", &l)
+	defer client.Close()
+	migrator, err := client.Migrator()
+	require.NoError(t, err)
+	check := migrationsCheck(migrator)
+	ctx := context.Background()
+
+	t.Run("should fail while migrations are pending", func(t *testing.T) {
+		err := check(ctx)
+
+		assert.ErrorContains(t, err, "pending migrations: 20250401120000_")
+	})
+
+	t.Run("should pass once every migration is applied", func(t *testing.T) {
+		_, err := migrator.Up(ctx)
+		require.NoError(t, err)
+
+		assert.NoError(t, check(ctx))
+	})
+}
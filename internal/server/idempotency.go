@@ -0,0 +1,209 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/logger"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
+)
+
+// Longest `Idempotency-Key` accepted, the keys are meant to be UUIDs
+const maxIdempotencyKeyLength = 255
+
+// Headers of a response stored along with its body, the others describe the
+// request that got it
+var replayedHeaders = []string{"Content-Type", "ETag", "Last-Modified", "Location"}
+
+// Passes the response through, keeping a copy of its body
+type teeWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *teeWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *teeWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Makes `POST` requests sent with an `Idempotency-Key` header safe to retry.
+// The response to the first request is stored for `ttl` and replayed to the
+// retries, marked by an `Idempotent-Replayed: true` header.
+//
+// A key reused for another request, i.e. another route or body, is answered
+// with `422 Unprocessable Entity`, and a retry arriving while the first
+// request is processed with `409 Conflict`. `5xx` responses aren't stored,
+// the request may be retried with the same key.
+func idempotency(store database.IdempotencyRepository, ttl time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader("Idempotency-Key")
+		if ctx.Request.Method != http.MethodPost || key == "" {
+			ctx.Next()
+			return
+		}
+
+		reqContext := ctx.Request.Context()
+		log := logger.FromContext(reqContext).
+			With().
+			Str("middleware", "idempotency").
+			Str("idempotency_key", key).
+			Logger()
+
+		if len(key) > maxIdempotencyKeyLength {
+			log.Info().
+				Msg("idempotency key too long")
+
+			respondProblem(ctx, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+			ctx.Abort()
+			return
+		}
+
+		body, err := requestBody(ctx)
+		if err != nil {
+			log.Info().
+				Err(err).
+				Msg("error reading request body")
+
+			var tooLargeErr *http.MaxBytesError
+			if errors.As(err, &tooLargeErr) {
+				renderProblem(ctx, validationProblem(err, "request body"))
+			} else {
+				respondProblem(ctx, http.StatusBadRequest, "request body could not be read")
+			}
+			ctx.Abort()
+			return
+		}
+		fingerprint := requestFingerprint(ctx.Request, body)
+
+		held, err := store.IdempotencyKeyClaim(reqContext, models.IdempotencyRecord{
+			Key:         key,
+			Fingerprint: fingerprint,
+			ExpiresAt:   time.Now().Add(ttl),
+		})
+		if err != nil {
+			log.Error().
+				Err(err).
+				Msg("error claiming idempotency key")
+
+			respondProblem(ctx, http.StatusServiceUnavailable, "service unavailable")
+			ctx.Abort()
+			return
+		}
+
+		if held != nil {
+			switch {
+			case held.Fingerprint != fingerprint:
+				log.Info().
+					Msg("idempotency key reused for another request")
+
+				respondProblem(ctx, http.StatusUnprocessableEntity, "Idempotency-Key was used for another request")
+			case held.Status == 0:
+				log.Info().
+					Msg("request with the idempotency key in progress")
+
+				respondProblem(ctx, http.StatusConflict, "a request with this Idempotency-Key is in progress")
+			default:
+				log.Info().
+					Int("status", held.Status).
+					Msg("replaying stored response")
+
+				for name, values := range held.Header {
+					for _, value := range values {
+						ctx.Writer.Header().Add(name, value)
+					}
+				}
+				ctx.Header("Idempotent-Replayed", "true")
+				ctx.Status(held.Status)
+				ctx.Writer.Write(held.Body)
+			}
+
+			ctx.Abort()
+			return
+		}
+
+		// The response is stored even when the client went away, that's
+		// when it will retry
+		storeContext := context.WithoutCancel(reqContext)
+		release := func() {
+			if err := store.IdempotencyKeyRelease(storeContext, key); err != nil {
+				log.Error().
+					Err(err).
+					Msg("error releasing idempotency key")
+			}
+		}
+		defer func() {
+			if v := recover(); v != nil {
+				release()
+				panic(v)
+			}
+		}()
+
+		writer := &teeWriter{ResponseWriter: ctx.Writer, body: &bytes.Buffer{}}
+		ctx.Writer = writer
+		ctx.Next()
+
+		if writer.Status() >= http.StatusInternalServerError {
+			release()
+			return
+		}
+
+		header := http.Header{}
+		for _, name := range replayedHeaders {
+			if values := writer.Header().Values(name); len(values) > 0 {
+				header[name] = values
+			}
+		}
+
+		err = store.IdempotencyKeyComplete(storeContext, models.IdempotencyRecord{
+			Key:         key,
+			Fingerprint: fingerprint,
+			Status:      writer.Status(),
+			Header:      header,
+			Body:        writer.body.Bytes(),
+		})
+		if err != nil {
+			log.Error().
+				Err(err).
+				Msg("error storing response for idempotency key")
+		}
+	}
+}
+
+// Hash of what makes a request, its method, path, query and body
+func requestFingerprint(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + " " + req.URL.RequestURI() + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Body of the request, as read by the logger middleware. Read here and kept
+// for binding otherwise
+func requestBody(ctx *gin.Context) ([]byte, error) {
+	if body, ok := ctx.Get(gin.BodyBytesKey); ok {
+		return body.([]byte), nil
+	}
+
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Set(gin.BodyBytesKey, body)
+
+	return body, nil
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/inmemory"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
+)
+
+func Test_idempotency(t *testing.T) {
+	calls := 0
+	status := http.StatusCreated
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(idempotency(inmemory.New(time.Now), time.Hour))
+	router.POST("/resources", func(ctx *gin.Context) {
+		calls++
+		ctx.Header("Location", "/resources/1")
+		ctx.JSON(status, gin.H{"call": calls})
+	})
+	router.POST("/panic", func(ctx *gin.Context) {
+		calls++
+		panic("You've met a terrible fate, haven't you?")
+	})
+
+	post := func(path string, key string, body string) *httptest.ResponseRecorder {
+		req := addLoggerToContext(httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		return w
+	}
+
+	t.Run("should replay the response to the retries", func(t *testing.T) {
+		calls = 0
+
+		first := post("/resources", "replayed", `{"title":"coolio"}`)
+		retry := post("/resources", "replayed", `{"title":"coolio"}`)
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, "/resources/1", retry.Header().Get("Location"))
+		assert.Equal(t, "application/json; charset=utf-8", retry.Header().Get("Content-Type"))
+		assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	})
+
+	t.Run("should replay client errors too", func(t *testing.T) {
+		calls = 0
+		status = http.StatusConflict
+		defer func() {
+			status = http.StatusCreated
+		}()
+
+		post("/resources", "conflict", `{}`)
+		retry := post("/resources", "conflict", `{}`)
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusConflict, retry.Code)
+	})
+
+	t.Run("should reject a key reused for another request", func(t *testing.T) {
+		calls = 0
+
+		post("/resources", "reused", `{"title":"coolio"}`)
+		w := post("/resources", "reused", `{"title":"another coolio"}`)
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "Idempotency-Key was used for another request")
+	})
+
+	t.Run("should process every request without a key", func(t *testing.T) {
+		calls = 0
+
+		post("/resources", "", `{}`)
+		post("/resources", "", `{}`)
+
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("should let requests that failed on the server be retried", func(t *testing.T) {
+		calls = 0
+		status = http.StatusServiceUnavailable
+		post("/resources", "unavailable", `{}`)
+		status = http.StatusCreated
+
+		retry := post("/resources", "unavailable", `{}`)
+		post("/panic", "panic", `{}`)
+		post("/panic", "panic", `{}`)
+
+		assert.Equal(t, 4, calls)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+	})
+
+	t.Run("should reject keys longer than 255 characters", func(t *testing.T) {
+		calls = 0
+
+		w := post("/resources", strings.Repeat("k", 256), `{}`)
+
+		assert.Equal(t, 0, calls)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("should answer 409 while the first request is in progress", func(t *testing.T) {
+		oldClaimFn := inmemory.InMemoryIdempotencyKeyClaimFn
+		defer func() {
+			inmemory.InMemoryIdempotencyKeyClaimFn = oldClaimFn
+		}()
+		inmemory.InMemoryIdempotencyKeyClaimFn = func(ctx context.Context, record models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+			return &record, nil
+		}
+		calls = 0
+
+		w := post("/resources", "in progress", `{}`)
+
+		assert.Equal(t, 0, calls)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("should return 503 if the key can't be claimed", func(t *testing.T) {
+		oldClaimFn := inmemory.InMemoryIdempotencyKeyClaimFn
+		defer func() {
+			inmemory.InMemoryIdempotencyKeyClaimFn = oldClaimFn
+		}()
+		inmemory.InMemoryIdempotencyKeyClaimFn = func(ctx context.Context, record models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+			return nil, errors.New("something terrible happened")
+		}
+		calls = 0
+
+		w := post("/resources", "unavailable store", `{}`)
+
+		assert.Equal(t, 0, calls)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+}
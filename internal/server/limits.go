@@ -0,0 +1,18 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Stops reading request bodies past `max` bytes. Reading further fails with
+// an `*http.MaxBytesError`, which binding turns into a `413 Content Too Large`
+// problem, see `validationProblem`
+func limitBody(max int64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.Body != nil {
+			ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, max)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/inmemory"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/logger"
+)
+
+func Test_limitBody(t *testing.T) {
+	resetDB(t)
+	oldRequestIDGenerator := logger.MiddlewareRequestIDGenerator
+	logger.MiddlewareRequestIDGenerator = func() string {
+		return "0195d6a2-7e3c-7b4e-9f1a-3c2b1a0d9e8f"
+	}
+	defer func() {
+		logger.MiddlewareRequestIDGenerator = oldRequestIDGenerator
+	}()
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(limitBody(64))
+	router.Use(logger.NewMiddleware(app.Logger))
+	router.Use(idempotency(inmemory.New(time.Now), time.Hour))
+	router.POST("/users", app.UserCreate)
+
+	user := `{"name":"Jane Roe","email":"janeroe@gmail.com"}`
+	tooLarge := `{"name":"` + strings.Repeat("Jane ", 20) + `","email":"janeroe@gmail.com"}`
+
+	tests := []struct {
+		Name       string
+		Body       io.Reader
+		Key        string
+		StatusCode int
+	}{
+		{"should accept a body within the limit", strings.NewReader(user), "", http.StatusCreated},
+		{"should return 413 for a body over the limit", strings.NewReader(tooLarge), "", http.StatusRequestEntityTooLarge},
+		// Without a `Content-Length`, as when sent in chunks
+		{"should return 413 for a body of unknown length over the limit", io.MultiReader(strings.NewReader(tooLarge)), "", http.StatusRequestEntityTooLarge},
+		{"should return 413 for a body over the limit with an Idempotency-Key", strings.NewReader(tooLarge), "too large", http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users", tt.Body)
+			if tt.Key != "" {
+				req.Header.Set("Idempotency-Key", tt.Key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.StatusCode, w.Code)
+			snaps.MatchJSON(t, w.Body.String())
+		})
+	}
+}
@@ -2,15 +2,24 @@ package server
 
 import (
 	"github.com/gin-gonic/gin"
+
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/logger"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/tracing"
 )
 
 func (a *Application) RegisterMiddleware() {
 	r := a.Router
 
 	// GLOBAL
+	// Request counts and latency, outside of `Recovery` to count panics as
+	// the 500 they become
+	r.Use(a.Metrics.Middleware())
+	// Span of the request, before the logger so its logs carry the trace ID
+	r.Use(tracing.Middleware())
 	// Recover from panics
 	r.Use(gin.Recovery())
+	// Bounds the request body, before anything reads it
+	r.Use(limitBody(a.Config.MaxBodyBytes))
 	// Zerolog logger
 	r.Use(logger.NewMiddleware(a.Logger))
 }
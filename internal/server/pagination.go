@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// Envelope of every listing endpoint
+type listResponse[T any] struct {
+	Data       []T     `json:"data"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// Reads `?limit=` and `?after=` from the query string
+func parsePagination(ctx *gin.Context) (models.Pagination, error) {
+	page := models.Pagination{
+		Limit: defaultPageLimit,
+	}
+
+	if limitRaw, ok := ctx.GetQuery("limit"); ok {
+		limit, err := strconv.Atoi(limitRaw)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return page, &queryParamError{
+				Param:  "limit",
+				Reason: fmt.Sprintf("must be a number between 1 and %d", maxPageLimit),
+			}
+		}
+		page.Limit = limit
+	}
+
+	if afterRaw, ok := ctx.GetQuery("after"); ok {
+		cursor, err := decodeCursor(afterRaw)
+		if err != nil {
+			return page, &queryParamError{
+				Param:  "after",
+				Reason: "must be a cursor returned by a previous page",
+			}
+		}
+		page.After = cursor
+	}
+
+	return page, nil
+}
+
+// Cursors are opaque to clients, they should only pass back what we gave them
+func encodeCursor(cursor *models.Cursor) *string {
+	if cursor == nil {
+		return nil
+	}
+
+	raw, _ := json.Marshal(cursor)
+	encoded := base64.RawURLEncoding.EncodeToString(raw)
+
+	return &encoded
+}
+
+func decodeCursor(encoded string) (*models.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor models.Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+
+var (
+	errUnsupportedMediaType = errors.New("unsupported media type")
+	errPatchNotAnObject     = errors.New("merge patch must be a JSON object")
+)
+
+// Returned when a merge patch sets a field to `null`
+type fieldRemovalError struct {
+	Field string
+}
+
+func (e *fieldRemovalError) Error() string {
+	return fmt.Sprintf("field `%s` cannot be removed", e.Field)
+}
+
+// Binds a JSON Merge Patch (RFC 7396) into `obj`, whose pointer fields stay
+// nil when absent from the patch.
+//
+// Every field of our resources is required, so removing one with `null` is
+// rejected rather than silently ignored.
+func bindMergePatch(ctx *gin.Context, obj any) error {
+	switch ctx.ContentType() {
+	case mergePatchContentType, gin.MIMEJSON:
+	default:
+		return errUnsupportedMediaType
+	}
+
+	if err := ctx.ShouldBindBodyWithJSON(obj); err != nil {
+		return err
+	}
+
+	// Body is cached by `ShouldBindBodyWithJSON`
+	body := ctx.MustGet(gin.BodyBytesKey).([]byte)
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return errPatchNotAnObject
+	}
+
+	for name, value := range fields {
+		if string(value) == "null" {
+			return &fieldRemovalError{Field: name}
+		}
+	}
+
+	return nil
+}
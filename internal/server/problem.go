@@ -0,0 +1,178 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/logger"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem details (RFC 7807), the body of every error response.
+//
+// Every problem is of the generic `about:blank` type, so the title is the
+// status text and `detail` says what went wrong.
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Fields of the request body that failed validation
+	Errors []fieldError `json:"errors,omitempty"`
+	// Members specific to the problem, sent alongside the standard ones
+	Extensions map[string]any `json:"-"`
+}
+
+func (p problem) MarshalJSON() ([]byte, error) {
+	type standard problem
+	body, err := json.Marshal(standard(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return body, err
+	}
+
+	members := map[string]any{}
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+	if err := json.Unmarshal(body, &members); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(members)
+}
+
+// A field of the request body that failed validation, `rule` is the
+// validation that failed, e.g. `required` or `email`
+type fieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+}
+
+// Report validation errors with the JSON name of the fields
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+
+	return name
+}
+
+// Sends `p` as the error response, filling in the members derived from its
+// status and the request
+func renderProblem(ctx *gin.Context, p problem) {
+	p.Type = "about:blank"
+	p.Title = http.StatusText(p.Status)
+	if requestID := logger.RequestIDFromContext(ctx.Request.Context()); requestID != "" {
+		p.Instance = "urn:uuid:" + requestID
+	}
+
+	ctx.Header("Content-Type", problemContentType)
+	ctx.JSON(p.Status, p)
+}
+
+// Sends a problem with the given status and detail
+func respondProblem(ctx *gin.Context, status int, detail string) {
+	renderProblem(ctx, problem{Status: status, Detail: detail})
+}
+
+// Sends a `422 Unprocessable Entity` problem for a request body that could
+// not be bound, listing the fields that failed validation
+func respondValidationProblem(ctx *gin.Context, err error) {
+	renderProblem(ctx, validationProblem(err, "request body"))
+}
+
+// Problem for a JSON document that could not be bound, `subject` names the
+// document in the detail
+func validationProblem(err error, subject string) problem {
+	p := problem{
+		Status: http.StatusUnprocessableEntity,
+		Detail: "invalid " + subject,
+		Errors: fieldErrors(err),
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var tooLargeErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLargeErr):
+		p.Status = http.StatusRequestEntityTooLarge
+		p.Detail = fmt.Sprintf("%s must be at most %d bytes", subject, tooLargeErr.Limit)
+	case len(p.Errors) > 0:
+	case errors.Is(err, io.EOF):
+		p.Detail = subject + " is empty"
+	case errors.As(err, &typeErr), errors.Is(err, errPatchNotAnObject):
+		p.Detail = subject + " must be a JSON object"
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		p.Detail = subject + " is not valid JSON"
+	}
+
+	return p
+}
+
+// Fields behind a binding error, if it is about fields at all
+func fieldErrors(err error) []fieldError {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var removalErr *fieldRemovalError
+
+	switch {
+	case errors.As(err, &validationErrs):
+		result := make([]fieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			result = append(result, fieldError{
+				Field: fe.Field(),
+				Rule:  fe.Tag(),
+				Param: fe.Param(),
+			})
+		}
+
+		return result
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return []fieldError{{Field: typeErr.Field, Rule: "type", Param: jsonType(typeErr.Type)}}
+	case errors.As(err, &removalErr):
+		return []fieldError{{Field: removalErr.Field, Rule: "required"}}
+	}
+
+	return nil
+}
+
+// Name of the JSON type Go values of type `t` are decoded from
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
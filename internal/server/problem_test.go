@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/logger"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
+)
+
+func Test_renderProblem(t *testing.T) {
+	router := gin.New()
+	router.GET("/problem", func(ctx *gin.Context) {
+		renderProblem(ctx, problem{
+			Status:     http.StatusConflict,
+			Detail:     "user has posts",
+			Extensions: map[string]any{"posts": 2},
+		})
+	})
+
+	t.Run("should send a problem document identified by the request ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/problem", nil)
+		req = req.WithContext(logger.WithRequestID(req.Context(), "0b5e4c1a-7d2f-4f4e-9c3b-2a6d8e1f0c9b"))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+		snaps.MatchJSON(t, w.Body.String())
+	})
+
+	t.Run("should leave out the instance without a request ID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/problem", nil))
+
+		assert.NotContains(t, w.Body.String(), "instance")
+	})
+}
+
+func Test_respondValidationProblem(t *testing.T) {
+	router := gin.New()
+	router.POST("/posts", func(ctx *gin.Context) {
+		var post models.Post
+		if err := ctx.ShouldBindBodyWithJSON(&post); err != nil {
+			respondValidationProblem(ctx, err)
+			return
+		}
+
+		ctx.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		Name        string
+		RequestBody string
+	}{
+		{"should list every field failing validation", `{"title":"coolio"}`},
+		{"should list fields of the wrong type", `{"title":"coolio","content":"coolest content","user_id":"1"}`},
+		{"should report an empty body", ``},
+		{"should report a body that is not an object", `[]`},
+		{"should report malformed JSON", `{"title":`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(tt.RequestBody))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+			snaps.MatchJSON(t, w.Body.String())
+		})
+	}
+}
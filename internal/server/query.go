@@ -0,0 +1,288 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Describes which query parameter was wrong and why
+type queryParamError struct {
+	Param  string
+	Reason string
+}
+
+func (e *queryParamError) Error() string {
+	return fmt.Sprintf("invalid query parameter `%s`: %s", e.Param, e.Reason)
+}
+
+// Query parameters accepted by `GET /users`
+var userQueryParams = map[string]bool{
+	"limit":   true,
+	"after":   true,
+	"include": true,
+}
+
+// Query parameters accepted by `GET /posts`
+var postQueryParams = map[string]bool{
+	"limit":          true,
+	"after":          true,
+	"user_id":        true,
+	"created_after":  true,
+	"created_before": true,
+	"title_contains": true,
+	"sort":           true,
+	"include":        true,
+}
+
+// Query parameters accepted by `GET /users/:id/posts`, the user comes from the path
+var userPostQueryParams = map[string]bool{
+	"limit":          true,
+	"after":          true,
+	"created_after":  true,
+	"created_before": true,
+	"title_contains": true,
+	"sort":           true,
+	"include":        true,
+}
+
+// Fields `GET /posts` can be sorted by
+var postSortFields = map[string]bool{
+	models.PostFieldID:        true,
+	models.PostFieldTitle:     true,
+	models.PostFieldCreatedAt: true,
+	models.PostFieldUpdatedAt: true,
+}
+
+// Reads the filters, sorting and pagination of a post listing, e.g.
+//
+//	?user_id=1&created_after=2025-01-01T00:00:00Z&title_contains=go&sort=-created_at,title
+//
+// Any parameter not in `allowed` is rejected.
+func parsePostQuery(ctx *gin.Context, allowed map[string]bool) (models.PostQuery, error) {
+	var query models.PostQuery
+
+	for param := range ctx.Request.URL.Query() {
+		if !allowed[param] {
+			return query, &queryParamError{Param: param, Reason: "unknown parameter"}
+		}
+	}
+
+	page, err := parsePagination(ctx)
+	if err != nil {
+		return query, err
+	}
+	query.Pagination = page
+
+	if raw, ok := ctx.GetQuery("user_id"); ok {
+		userID, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return query, &queryParamError{Param: "user_id", Reason: "must be a positive integer"}
+		}
+		query.UserID = &userID
+	}
+
+	if query.CreatedAfter, err = parseTimeParam(ctx, "created_after"); err != nil {
+		return query, err
+	}
+
+	if query.CreatedBefore, err = parseTimeParam(ctx, "created_before"); err != nil {
+		return query, err
+	}
+
+	if raw, ok := ctx.GetQuery("title_contains"); ok {
+		if raw == "" {
+			return query, &queryParamError{Param: "title_contains", Reason: "must not be empty"}
+		}
+		query.TitleContains = raw
+	}
+
+	if raw, ok := ctx.GetQuery("sort"); ok {
+		if query.Sort, err = parseSort(raw, postSortFields); err != nil {
+			return query, err
+		}
+	}
+
+	if query.Include, err = parsePostInclude(ctx); err != nil {
+		return query, err
+	}
+
+	// The cursor must come from a page with the same ordering
+	if query.After != nil {
+		if err := checkCursorKeys(query.After, query.Sort); err != nil {
+			return query, err
+		}
+	}
+
+	return query, nil
+}
+
+// Reads the user whose posts `DELETE /posts` deletes, it is required so a
+// missing parameter can't delete every post
+func parsePostDeleteQuery(ctx *gin.Context) (uint64, error) {
+	for param := range ctx.Request.URL.Query() {
+		if param != "user_id" {
+			return 0, &queryParamError{Param: param, Reason: "unknown parameter"}
+		}
+	}
+
+	raw, ok := ctx.GetQuery("user_id")
+	if !ok {
+		return 0, &queryParamError{Param: "user_id", Reason: "is required"}
+	}
+	userID, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, &queryParamError{Param: "user_id", Reason: "must be a positive integer"}
+	}
+
+	return userID, nil
+}
+
+// Reads the pagination and embedded relations of `GET /users`, any other
+// parameter is rejected
+func parseUserQuery(ctx *gin.Context) (models.UserQuery, error) {
+	var query models.UserQuery
+
+	for param := range ctx.Request.URL.Query() {
+		if !userQueryParams[param] {
+			return query, &queryParamError{Param: param, Reason: "unknown parameter"}
+		}
+	}
+
+	page, err := parsePagination(ctx)
+	if err != nil {
+		return query, err
+	}
+	query.Pagination = page
+
+	if query.Include, err = parseUserInclude(ctx); err != nil {
+		return query, err
+	}
+
+	return query, nil
+}
+
+func parseUserInclude(ctx *gin.Context) (models.UserInclude, error) {
+	include, err := parseInclude(ctx, "posts")
+
+	return models.UserInclude{Posts: include["posts"]}, err
+}
+
+func parsePostInclude(ctx *gin.Context) (models.PostInclude, error) {
+	include, err := parseInclude(ctx, "user")
+
+	return models.PostInclude{User: include["user"]}, err
+}
+
+// Reads `?include=` as a comma separated list of relations among `allowed`
+func parseInclude(ctx *gin.Context, allowed ...string) (map[string]bool, error) {
+	include := map[string]bool{}
+
+	raw, ok := ctx.GetQuery("include")
+	if !ok {
+		return include, nil
+	}
+
+	for _, relation := range strings.Split(raw, ",") {
+		if !slices.Contains(allowed, relation) {
+			return nil, &queryParamError{
+				Param:  "include",
+				Reason: fmt.Sprintf("cannot include %q", relation),
+			}
+		}
+		include[relation] = true
+	}
+
+	return include, nil
+}
+
+// Reads `?cascade=` of `DELETE /users/:id`, false when absent
+func parseCascade(ctx *gin.Context) (bool, error) {
+	raw, ok := ctx.GetQuery("cascade")
+	if !ok {
+		return false, nil
+	}
+
+	cascade, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, &queryParamError{Param: "cascade", Reason: "must be `true` or `false`"}
+	}
+
+	return cascade, nil
+}
+
+func parseTimeParam(ctx *gin.Context, param string) (*time.Time, error) {
+	raw, ok := ctx.GetQuery(param)
+	if !ok {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, &queryParamError{Param: param, Reason: "must be an RFC 3339 timestamp"}
+	}
+
+	return &t, nil
+}
+
+// Parses a comma separated list of fields, descending when prefixed by `-`
+func parseSort(raw string, allowed map[string]bool) ([]models.SortField, error) {
+	seen := map[string]bool{}
+	sort := []models.SortField{}
+
+	for _, part := range strings.Split(raw, ",") {
+		sf := models.SortField{Field: part}
+		if strings.HasPrefix(part, "-") {
+			sf = models.SortField{Field: part[1:], Desc: true}
+		}
+
+		if !allowed[sf.Field] {
+			return nil, &queryParamError{
+				Param:  "sort",
+				Reason: fmt.Sprintf("cannot sort by %q", sf.Field),
+			}
+		}
+		if seen[sf.Field] {
+			return nil, &queryParamError{
+				Param:  "sort",
+				Reason: fmt.Sprintf("%q is repeated", sf.Field),
+			}
+		}
+
+		seen[sf.Field] = true
+		sort = append(sort, sf)
+	}
+
+	return sort, nil
+}
+
+func checkCursorKeys(cursor *models.Cursor, sort []models.SortField) error {
+	expected := 0
+	for _, sf := range sort {
+		if sf.Field == models.PostFieldID {
+			continue
+		}
+
+		value, ok := cursor.Keys[sf.Field]
+		if !ok {
+			return &queryParamError{Param: "after", Reason: "cursor does not match `sort`"}
+		}
+		if sf.Field == models.PostFieldCreatedAt || sf.Field == models.PostFieldUpdatedAt {
+			if _, err := time.Parse(time.RFC3339Nano, value); err != nil {
+				return &queryParamError{Param: "after", Reason: "must be a cursor returned by a previous page"}
+			}
+		}
+		expected++
+	}
+
+	if len(cursor.Keys) != expected {
+		return &queryParamError{Param: "after", Reason: "cursor does not match `sort`"}
+	}
+
+	return nil
+}
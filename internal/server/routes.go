@@ -1,24 +1,79 @@
 package server
 
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
 func (a *Application) RegisterRoutes() {
 	r := a.Router
 
+	// Probes
+	r.GET("/livez", a.LivenessCheck)
+	r.GET("/readyz", a.ReadinessCheck)
 	r.GET("/health", a.HealthCheck)
+	// Served on its own port instead when `Config.MetricsPort` is set
+	if a.Config.MetricsPort == 0 {
+		r.GET("/metrics", gin.WrapH(a.Metrics.Handler()))
+	}
+
+	// 304 Not Modified for unchanged GET responses, and the stored response
+	// to POSTs retried with an Idempotency-Key, unlike the probes above
+	conditional := conditionalGET()
+	idempotent := idempotency(a.Idempotency, a.Config.IdempotencyKeyTTL)
 
 	// Users
-	userRoutes := r.Group("/users")
+	userRoutes := r.Group("/users", conditional, idempotent)
 
 	userRoutes.POST("", a.UserCreate)
 	userRoutes.GET("", a.UserGetAll)
 	userRoutes.GET("/:id", a.UserGetByID)
 	userRoutes.DELETE("/:id", a.UserDeleteByID)
 	userRoutes.PUT("/:id", a.UserUpdateByID)
+	userRoutes.PATCH("/:id", a.UserPatchByID)
+	userRoutes.POST("/:id/restore", a.UserRestoreByID)
+	userRoutes.GET("/:id/posts", a.UserPostGetAll)
+	userRoutes.POST("/:id/posts", a.UserPostCreate)
+	customMethod(r, "/users", "batch", idempotent, a.UserCreateBatch)
 
 	// Posts
-	postRoutes := r.Group("/posts")
+	postRoutes := r.Group("/posts", conditional, idempotent)
 	postRoutes.POST("", a.PostCreate)
 	postRoutes.GET("", a.PostGetAll)
+	postRoutes.DELETE("", a.PostDeleteAll)
 	postRoutes.GET("/:id", a.PostGetByID)
 	postRoutes.DELETE("/:id", a.PostDeleteByID)
 	postRoutes.PUT("/:id", a.PostUpdateByID)
+	postRoutes.PATCH("/:id", a.PostPatchByID)
+	postRoutes.POST("/:id/restore", a.PostRestoreByID)
+	customMethod(r, "/posts", "batch", idempotent, a.PostCreateBatch)
+
+	// Unknown routes and methods answer with a problem like every other error
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(routeNotFound)
+	r.NoMethod(methodNotAllowed)
+}
+
+func routeNotFound(ctx *gin.Context) {
+	respondProblem(ctx, http.StatusNotFound, "route not found")
+}
+
+func methodNotAllowed(ctx *gin.Context) {
+	respondProblem(ctx, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+// Registers `POST <collection>:<verb>`, a custom method of the collection as
+// in https://google.aip.dev/136. Gin can't escape the colon, the route is a
+// parameter matching anything right after the collection, so any other
+// suffix is not found before reaching `handlers`.
+func customMethod(r gin.IRoutes, collection string, verb string, handlers ...gin.HandlerFunc) {
+	matchVerb := func(ctx *gin.Context) {
+		if ctx.Param(verb) != ":"+verb {
+			routeNotFound(ctx)
+			ctx.Abort()
+		}
+	}
+
+	r.POST(collection+":"+verb, append([]gin.HandlerFunc{matchVerb}, handlers...)...)
 }
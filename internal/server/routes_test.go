@@ -0,0 +1,142 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/metrics"
+)
+
+// Copy of the app with the sample data, every middleware and every route, as
+// `New` sets it up
+func newRoutedApp(t *testing.T) Application {
+	t.Helper()
+
+	resetDB(t)
+	a := app
+	c := *app.Config
+	c.MaxBodyBytes = 1 << 20
+	c.IdempotencyKeyTTL = time.Hour
+	a.Config = &c
+	a.Router = gin.New()
+	a.Metrics = metrics.New()
+	a.RegisterMiddleware()
+	a.RegisterRoutes()
+
+	return a
+}
+
+func Test_Application_RegisterRoutes(t *testing.T) {
+	a := newRoutedApp(t)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+
+		return w
+	}
+
+	t.Run("should answer unchanged user and post GETs with 304", func(t *testing.T) {
+		for _, path := range []string{"/users", "/users/1", "/posts", "/posts/1"} {
+			w := serve(httptest.NewRequest(http.MethodGet, path, nil))
+			etag := w.Header().Get("ETag")
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("If-None-Match", etag)
+
+			assert.NotEmpty(t, etag, path)
+			assert.Equal(t, http.StatusNotModified, serve(req).Code, path)
+		}
+	})
+
+	t.Run("should not make the probes and metrics conditional", func(t *testing.T) {
+		for _, path := range []string{"/livez", "/readyz", "/health", "/metrics"} {
+			w := serve(httptest.NewRequest(http.MethodGet, path, nil))
+
+			assert.Equal(t, http.StatusOK, w.Code, path)
+			assert.Empty(t, w.Header().Get("ETag"), path)
+		}
+	})
+
+	// The custom methods share their prefix with the other user routes
+	t.Run("should route the POSTs under /users", func(t *testing.T) {
+		tests := []struct {
+			Method     string
+			Path       string
+			Body       string
+			StatusCode int
+		}{
+			{http.MethodPost, "/users:batch", `{"items":[{"name":"Tom Doe","email":"tomdoe@gmail.com"}]}`, http.StatusCreated},
+			{http.MethodPost, "/users/1/posts", `{"title":"coolio","content":"coolest content"}`, http.StatusCreated},
+			{http.MethodDelete, "/users/1?cascade=true", "", http.StatusNoContent},
+			{http.MethodPost, "/users/1/restore", "", http.StatusOK},
+			{http.MethodPost, "/users:frobnicate", `{}`, http.StatusNotFound},
+		}
+
+		for _, tt := range tests {
+			w := serve(httptest.NewRequest(tt.Method, tt.Path, strings.NewReader(tt.Body)))
+
+			assert.Equal(t, tt.StatusCode, w.Code, tt.Method+" "+tt.Path)
+		}
+	})
+
+	t.Run("should answer unknown routes and methods with a problem", func(t *testing.T) {
+		tests := []struct {
+			Method     string
+			Path       string
+			StatusCode int
+		}{
+			{http.MethodGet, "/nowhere", http.StatusNotFound},
+			{http.MethodPost, "/users:frobnicate", http.StatusNotFound},
+			{http.MethodPut, "/users", http.StatusMethodNotAllowed},
+			{http.MethodDelete, "/livez", http.StatusMethodNotAllowed},
+		}
+
+		for _, tt := range tests {
+			w := serve(httptest.NewRequest(tt.Method, tt.Path, strings.NewReader(`{}`)))
+
+			assert.Equal(t, tt.StatusCode, w.Code, tt.Method+" "+tt.Path)
+			assert.Equal(t, problemContentType, w.Header().Get("Content-Type"), tt.Method+" "+tt.Path)
+			assert.Contains(t, w.Body.String(), fmt.Sprintf(`"status":%d`, tt.StatusCode), tt.Method+" "+tt.Path)
+		}
+	})
+
+	t.Run("should only claim Idempotency-Keys on the user and post routes", func(t *testing.T) {
+		for i, path := range []string{"/nowhere", "/users:frobnicate", "/livez"} {
+			key := fmt.Sprintf("4e1f8d5c-0000-4000-8000-00000000000%d", i)
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+			req.Header.Set("Idempotency-Key", key)
+			serve(req)
+
+			req = httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(fmt.Sprintf(`{"name":"Idem Doe","email":"idem%d@gmail.com"}`, i)))
+			req.Header.Set("Idempotency-Key", key)
+			w := serve(req)
+
+			assert.Equal(t, http.StatusCreated, w.Code, path)
+			assert.Empty(t, w.Header().Get("Idempotent-Replayed"), path)
+		}
+	})
+
+	t.Run("should replay POSTs retried with an Idempotency-Key", func(t *testing.T) {
+		for _, tt := range []struct{ Path, Body string }{
+			{"/posts", `{"title":"coolio","content":"coolest content","user_id":2}`},
+			{"/posts:batch", `{"items":[{"title":"coolio","content":"coolest content","user_id":2}]}`},
+		} {
+			var w *httptest.ResponseRecorder
+			for range 2 {
+				req := httptest.NewRequest(http.MethodPost, tt.Path, strings.NewReader(tt.Body))
+				req.Header.Set("Idempotency-Key", "replay "+tt.Path)
+				w = serve(req)
+			}
+
+			assert.Equal(t, http.StatusCreated, w.Code, tt.Path)
+			assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"), tt.Path)
+		}
+	})
+}
@@ -1,18 +1,22 @@
 package server
 
 import (
+	"context"
 	"net/http"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gkampitakis/go-snaps/snaps"
 	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
 
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/config"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/inmemory"
 	"github.com/danilevy1212/UserPostApi-Challenge/internal/logger"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
 )
 
 var app Application
@@ -21,14 +25,52 @@ func addLoggerToContext(req *http.Request) *http.Request {
 	return req.WithContext(logger.WithContext(req.Context(), app.Logger))
 }
 
+// Time of every write, fixed so snapshots and ETags are stable
+func testClock() time.Time {
+	return time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+}
+
+// Replaces the repository with a fresh one holding the sample data: users 1
+// and 2 with posts 1, 2 and 3, and user 3 without posts
+func resetDB(t *testing.T) {
+	t.Helper()
+
+	db := inmemory.New(testClock)
+	ctx := context.Background()
+
+	for _, u := range []models.User{
+		{Name: "John Doe", Email: "johnnydoe@gmail.com"},
+		{Name: "Daniel Levy Moreno", Email: "danielmorenolevy@gmail.com"},
+		{Name: "Jane Doe", Email: "janedoe@gmail.com"},
+	} {
+		_, err := db.UserCreate(ctx, u)
+		require.NoError(t, err)
+	}
+
+	for _, p := range []models.Post{
+		{Title: "coolio", Content: "coolest content", UserID: 1},
+		{Title: "another coolio", Content: "another coolest content", UserID: 1},
+		{Title: "more coolio", Content: "coolest content?", UserID: 2},
+	} {
+		_, err := db.PostCreate(ctx, p)
+		require.NoError(t, err)
+	}
+
+	app.Users = db
+	app.Posts = db
+	app.Health = db
+	app.Idempotency = db
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode)
 
 	// Set testing config
 	config.ConfigFetcher = func() config.Config {
 		return config.Config{
-			IsDev: true,
-			Port:  8080,
+			IsDev:         true,
+			Port:          8080,
+			MaxBatchItems: 1000,
 		}
 	}
 
@@ -36,8 +78,13 @@ func TestMain(m *testing.M) {
 	l := zerolog.Nop()
 	app.Config = &c
 	app.Router = gin.New()
-	app.DB = &inmemory.InMemoryDB{}
+	db := inmemory.New(testClock)
+	app.Users = db
+	app.Posts = db
+	app.Health = db
+	app.Idempotency = db
 	app.Logger = &l
+	app.shuttingDown = &atomic.Bool{}
 
 	// Freeze time
 	zerolog.TimestampFunc = func() time.Time {
@@ -0,0 +1,235 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
+)
+
+// Repository that starts a span for every call to the one it wraps
+type repository struct {
+	db database.DBRepository
+	// Database behind `db`, e.g. `postgresql`
+	system string
+}
+
+// Wraps `db` so each call gets its own span, named after the method, e.g.
+// `repository.UserCreate`. `system` names the database behind it
+func Repository(db database.DBRepository, system string) database.DBRepository {
+	return &repository{db: db, system: system}
+}
+
+func (r *repository) start(ctx context.Context, method string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, "repository."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemKey.String(r.system)),
+	)
+}
+
+// Ends `span`, failing it if the call failed unexpectedly. A not found or a
+// conflict is an outcome of the call, not a failure
+func end(span trace.Span, err error) {
+	if database.IsUnexpected(err) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TRANSACTIONS
+// The calls made within the transaction get their spans as children of the
+// one of the transaction
+func (r *repository) WithTx(ctx context.Context, fn database.TxFunc) error {
+	ctx, span := r.start(ctx, "WithTx")
+	err := r.db.WithTx(ctx, func(ctx context.Context, tx database.DBRepository) error {
+		return fn(ctx, Repository(tx, r.system))
+	})
+	end(span, err)
+
+	return err
+}
+
+// HEALTH
+func (r *repository) Ping(ctx context.Context) error {
+	ctx, span := r.start(ctx, "Ping")
+	err := r.db.Ping(ctx)
+	end(span, err)
+
+	return err
+}
+
+// USERS
+func (r *repository) UserCreate(ctx context.Context, user models.User) (*models.User, error) {
+	ctx, span := r.start(ctx, "UserCreate")
+	result, err := r.db.UserCreate(ctx, user)
+	end(span, err)
+
+	return result, err
+}
+
+func (r *repository) UserCreateBulk(ctx context.Context, users []models.User, opts models.BulkOptions) ([]*models.User, error) {
+	ctx, span := r.start(ctx, "UserCreateBulk")
+	result, err := r.db.UserCreateBulk(ctx, users, opts)
+	end(span, err)
+
+	return result, err
+}
+
+func (r *repository) UserGetAll(ctx context.Context, query models.UserQuery) (*models.Page[*models.User], error) {
+	ctx, span := r.start(ctx, "UserGetAll")
+	result, err := r.db.UserGetAll(ctx, query)
+	end(span, err)
+
+	return result, err
+}
+
+func (r *repository) UserGetByID(ctx context.Context, id uint64, include models.UserInclude) (*models.User, error) {
+	ctx, span := r.start(ctx, "UserGetByID")
+	result, err := r.db.UserGetByID(ctx, id, include)
+	end(span, err)
+
+	return result, err
+}
+
+func (r *repository) UserGetPosts(ctx context.Context, id uint64, query models.PostQuery) (*models.Page[*models.Post], error) {
+	ctx, span := r.start(ctx, "UserGetPosts")
+	result, err := r.db.UserGetPosts(ctx, id, query)
+	end(span, err)
+
+	return result, err
+}
+
+func (r *repository) UserDeleteByID(ctx context.Context, id uint64, opts models.DeleteOptions) error {
+	ctx, span := r.start(ctx, "UserDeleteByID")
+	err := r.db.UserDeleteByID(ctx, id, opts)
+	end(span, err)
+
+	return err
+}
+
+func (r *repository) UserUpdate(ctx context.Context, user models.UserUpdate) (*models.User, error) {
+	ctx, span := r.start(ctx, "UserUpdate")
+	result, err := r.db.UserUpdate(ctx, user)
+	end(span, err)
+
+	return result, err
+}
+
+func (r *repository) UserPatch(ctx context.Context, patch models.UserPatch) (*models.User, error) {
+	ctx, span := r.start(ctx, "UserPatch")
+	result, err := r.db.UserPatch(ctx, patch)
+	end(span, err)
+
+	return result, err
+}
+
+func (r *repository) UserRestoreByID(ctx context.Context, id uint64) (*models.User, error) {
+	ctx, span := r.start(ctx, "UserRestoreByID")
+	result, err := r.db.UserRestoreByID(ctx, id)
+	end(span, err)
+
+	return result, err
+}
+
+// POSTS
+func (r *repository) PostCreate(ctx context.Context, post models.Post) (*models.Post, error) {
+	ctx, span := r.start(ctx, "PostCreate")
+	result, err := r.db.PostCreate(ctx, post)
+	end(span, err)
+
+	return result, err
+}
+
+func (r *repository) PostCreateBulk(ctx context.Context, posts []models.Post, opts models.BulkOptions) ([]*models.Post, error) {
+	ctx, span := r.start(ctx, "PostCreateBulk")
+	result, err := r.db.PostCreateBulk(ctx, posts, opts)
+	end(span, err)
+
+	return result, err
+}
+
+func (r *repository) PostGetAll(ctx context.Context, query models.PostQuery) (*models.Page[*models.Post], error) {
+	ctx, span := r.start(ctx, "PostGetAll")
+	result, err := r.db.PostGetAll(ctx, query)
+	end(span, err)
+
+	return result, err
+}
+
+func (r *repository) PostGetByID(ctx context.Context, id uint64, include models.PostInclude) (*models.Post, error) {
+	ctx, span := r.start(ctx, "PostGetByID")
+	result, err := r.db.PostGetByID(ctx, id, include)
+	end(span, err)
+
+	return result, err
+}
+
+func (r *repository) PostDeleteByID(ctx context.Context, id uint64, opts models.DeleteOptions) error {
+	ctx, span := r.start(ctx, "PostDeleteByID")
+	err := r.db.PostDeleteByID(ctx, id, opts)
+	end(span, err)
+
+	return err
+}
+
+func (r *repository) PostDeleteByUserID(ctx context.Context, userID uint64) (int, error) {
+	ctx, span := r.start(ctx, "PostDeleteByUserID")
+	result, err := r.db.PostDeleteByUserID(ctx, userID)
+	end(span, err)
+
+	return result, err
+}
+
+func (r *repository) PostUpdate(ctx context.Context, post models.PostUpdate) (*models.Post, error) {
+	ctx, span := r.start(ctx, "PostUpdate")
+	result, err := r.db.PostUpdate(ctx, post)
+	end(span, err)
+
+	return result, err
+}
+
+func (r *repository) PostPatch(ctx context.Context, patch models.PostPatch) (*models.Post, error) {
+	ctx, span := r.start(ctx, "PostPatch")
+	result, err := r.db.PostPatch(ctx, patch)
+	end(span, err)
+
+	return result, err
+}
+
+func (r *repository) PostRestoreByID(ctx context.Context, id uint64) (*models.Post, error) {
+	ctx, span := r.start(ctx, "PostRestoreByID")
+	result, err := r.db.PostRestoreByID(ctx, id)
+	end(span, err)
+
+	return result, err
+}
+
+// IDEMPOTENCY
+func (r *repository) IdempotencyKeyClaim(ctx context.Context, record models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	ctx, span := r.start(ctx, "IdempotencyKeyClaim")
+	result, err := r.db.IdempotencyKeyClaim(ctx, record)
+	end(span, err)
+
+	return result, err
+}
+
+func (r *repository) IdempotencyKeyComplete(ctx context.Context, record models.IdempotencyRecord) error {
+	ctx, span := r.start(ctx, "IdempotencyKeyComplete")
+	err := r.db.IdempotencyKeyComplete(ctx, record)
+	end(span, err)
+
+	return err
+}
+
+func (r *repository) IdempotencyKeyRelease(ctx context.Context, key string) error {
+	ctx, span := r.start(ctx, "IdempotencyKeyRelease")
+	err := r.db.IdempotencyKeyRelease(ctx, key)
+	end(span, err)
+
+	return err
+}
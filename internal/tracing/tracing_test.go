@@ -0,0 +1,195 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/database/repositories/inmemory"
+	"github.com/danilevy1212/UserPostApi-Challenge/internal/models"
+)
+
+// Records the spans ended from now on, until the test is done
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	_, err := Setup(context.Background(), "")
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	oldProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(oldProvider)
+	})
+
+	return recorder
+}
+
+func attributeOf(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+func Test_Setup(t *testing.T) {
+	t.Run("should export to the given endpoint", func(t *testing.T) {
+		oldProvider := otel.GetTracerProvider()
+		defer otel.SetTracerProvider(oldProvider)
+
+		shutdown, err := Setup(context.Background(), "http://localhost:4318")
+		require.NoError(t, err)
+
+		_, isSDK := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+		assert.True(t, isSDK)
+		assert.NoError(t, shutdown(context.Background()))
+	})
+
+	t.Run("should not export without an endpoint", func(t *testing.T) {
+		oldProvider := otel.GetTracerProvider()
+		defer otel.SetTracerProvider(oldProvider)
+
+		shutdown, err := Setup(context.Background(), "")
+		require.NoError(t, err)
+
+		assert.Equal(t, oldProvider, otel.GetTracerProvider())
+		assert.NoError(t, shutdown(context.Background()))
+	})
+}
+
+func Test_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := recordSpans(t)
+
+	var reqSpan trace.SpanContext
+	router := gin.New()
+	router.Use(Middleware())
+	router.Use(gin.Recovery())
+	router.GET("/users/:id", func(ctx *gin.Context) {
+		reqSpan = trace.SpanContextFromContext(ctx.Request.Context())
+		ctx.Status(http.StatusNoContent)
+	})
+	router.GET("/panic", func(ctx *gin.Context) {
+		panic("You've met a terrible fate, haven't you?")
+	})
+
+	t.Run("should continue the trace of the `traceparent` header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		span := spans[0]
+		assert.Equal(t, "GET /users/:id", span.Name())
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+		assert.Equal(t, "/users/:id", attributeOf(span, "http.route").AsString())
+		assert.Equal(t, "/users/1", attributeOf(span, "url.path").AsString())
+		assert.Equal(t, int64(http.StatusNoContent), attributeOf(span, "http.response.status_code").AsInt64())
+		assert.Equal(t, span.SpanContext(), reqSpan, "handlers should get the span of the request")
+	})
+
+	t.Run("should start a trace for requests without `traceparent`", func(t *testing.T) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/2", nil))
+
+		span := recorder.Ended()[1]
+		assert.True(t, span.SpanContext().IsValid())
+		assert.False(t, span.Parent().IsValid())
+	})
+
+	t.Run("should fail the span of requests that end in a server error", func(t *testing.T) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+		span := recorder.Ended()[2]
+		assert.Equal(t, codes.Error, span.Status().Code)
+		assert.Equal(t, int64(http.StatusInternalServerError), attributeOf(span, "http.response.status_code").AsInt64())
+	})
+
+	t.Run("should name the requests matching no route after their method", func(t *testing.T) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+		span := recorder.Ended()[3]
+		assert.Equal(t, "GET", span.Name())
+		assert.Equal(t, codes.Unset, span.Status().Code)
+	})
+}
+
+func Test_Repository(t *testing.T) {
+	recorder := recordSpans(t)
+	db := Repository(inmemory.New(time.Now), "memory")
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	defer parent.End()
+
+	t.Run("should start a span per call", func(t *testing.T) {
+		_, err := db.UserCreate(ctx, models.User{Name: "John Doe", Email: "johnnydoe@gmail.com"})
+		require.NoError(t, err)
+
+		span := recorder.Ended()[0]
+		assert.Equal(t, "repository.UserCreate", span.Name())
+		assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.Equal(t, "memory", attributeOf(span, "db.system").AsString())
+		assert.Equal(t, codes.Unset, span.Status().Code)
+	})
+
+	t.Run("should fail the span of the calls that fail", func(t *testing.T) {
+		oldUserGetByIDFn := inmemory.InMemoryUserGetByIDFn
+		defer func() {
+			inmemory.InMemoryUserGetByIDFn = oldUserGetByIDFn
+		}()
+		inmemory.InMemoryUserGetByIDFn = func(ctx context.Context, id uint64, include models.UserInclude) (*models.User, error) {
+			return nil, database.ErrUnavailable
+		}
+
+		_, err := db.UserGetByID(ctx, 1, models.UserInclude{})
+		require.ErrorIs(t, err, database.ErrUnavailable)
+
+		span := recorder.Ended()[1]
+		assert.Equal(t, codes.Error, span.Status().Code)
+		require.Len(t, span.Events(), 1)
+		assert.Equal(t, "exception", span.Events()[0].Name)
+	})
+
+	t.Run("should not fail the span of the calls answered with an expected error", func(t *testing.T) {
+		_, err := db.UserGetByID(ctx, 42, models.UserInclude{})
+		require.ErrorIs(t, err, database.ErrNotFound)
+
+		span := recorder.Ended()[2]
+		assert.Equal(t, codes.Unset, span.Status().Code)
+		assert.Empty(t, span.Events())
+	})
+
+	t.Run("should nest the calls within a transaction", func(t *testing.T) {
+		err := db.WithTx(ctx, func(ctx context.Context, tx database.DBRepository) error {
+			_, err := tx.PostCreate(ctx, models.Post{Title: "coolio", Content: "coolest content", UserID: 1})
+			return err
+		})
+		require.NoError(t, err)
+
+		spans := recorder.Ended()[3:]
+		require.Len(t, spans, 2)
+		assert.Equal(t, "repository.PostCreate", spans[0].Name())
+		assert.Equal(t, "repository.WithTx", spans[1].Name())
+		assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	})
+}